@@ -0,0 +1,142 @@
+package graphqlmultipart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Messages of the automatic persisted queries, they are the same Apollo uses,
+// so its clients know when to send the query again
+var (
+	// PersistedQueryNotFoundMessage is shown when a operation is sent by a
+	// hash that is not in the QueryStore
+	PersistedQueryNotFoundMessage = "PersistedQueryNotFound"
+
+	// PersistedQueryNotSupportedMessage is shown when a operation is sent by
+	// its hash, but persisted queries are not enabled
+	PersistedQueryNotSupportedMessage = "PersistedQueryNotSupported"
+
+	// PersistedQueryHashMismatchMessage is shown when a query is registered
+	// with a hash that is not its SHA-256
+	PersistedQueryHashMismatchMessage = "provided sha does not match query"
+
+	// PersistedQueryVersionMessage is shown when the version of the
+	// persistedQuery extension is not supported
+	PersistedQueryVersionMessage = "Unsupported persisted query version"
+)
+
+// DefaultQueryStoreSize is the number of queries kept by the QueryStore used
+// when none is given to WithPersistedQueries
+const DefaultQueryStoreSize = 1000
+
+// QueryStore keeps the queries registered by automatic persisted queries,
+// by their SHA-256 hash. It must be safe for concurrent use
+type QueryStore interface {
+	Get(hash string) (query string, ok bool)
+	Set(hash, query string)
+}
+
+// LRUQueryStore is a QueryStore that keeps the most recently used queries in
+// memory
+type LRUQueryStore struct {
+	cache *lru
+}
+
+// NewLRUQueryStore creates a LRUQueryStore that keeps up to size queries, a
+// size of zero or less uses the DefaultQueryStoreSize
+func NewLRUQueryStore(size int) *LRUQueryStore {
+	if size <= 0 {
+		size = DefaultQueryStoreSize
+	}
+	return &LRUQueryStore{cache: newLRU(size)}
+}
+
+// Get retrieves the query of the hash
+func (s *LRUQueryStore) Get(hash string) (string, bool) {
+	q, ok := s.cache.get(hash)
+	if !ok {
+		return "", false
+	}
+	return q.(string), true
+}
+
+// Set stores the query of the hash
+func (s *LRUQueryStore) Set(hash, query string) {
+	s.cache.set(hash, query)
+}
+
+// WithPersistedQueries enables automatic persisted queries, operations can be
+// sent with only the "persistedQuery" extension, and the ones sent with the
+// query and the extension are registered into the store. When store is nil,
+// a LRUQueryStore of DefaultQueryStoreSize is used. The queries are resolved
+// before the files are read, so the clients don't upload them for a miss
+func WithPersistedQueries(store QueryStore) Option {
+	return func(m *MultipartHandler) {
+		if store == nil {
+			store = NewLRUQueryStore(DefaultQueryStoreSize)
+		}
+		m.queryStore = store
+	}
+}
+
+// persistedQueryHash returns the hash of the "persistedQuery" extension of
+// the operation, if it has one
+func persistedQueryHash(op operationField) (string, float64, bool) {
+	pq, ok := op.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return "", 0, false
+	}
+
+	hash, _ := pq["sha256Hash"].(string)
+	version, _ := pq["version"].(float64)
+	return hash, version, true
+}
+
+// resolvePersistedQuery fills the query of a operation sent by its hash, or
+// registers it when sent with the query
+func (m MultipartHandler) resolvePersistedQuery(op *operationField, batching bool) error {
+	hash, version, ok := persistedQueryHash(*op)
+	if !ok {
+		return nil
+	}
+
+	if m.queryStore == nil {
+		if op.Query != "" {
+			return nil
+		}
+		return persistedQueryError(OutcomePersistedQueryNotSupported, PersistedQueryNotSupportedMessage, "PERSISTED_QUERY_NOT_SUPPORTED", batching)
+	}
+
+	if version != 1 {
+		return persistedQueryError(OutcomeInvalidPersistedQuery, PersistedQueryVersionMessage, "", batching)
+	}
+
+	if op.Query == "" {
+		q, ok := m.queryStore.Get(hash)
+		if !ok {
+			return persistedQueryError(OutcomePersistedQueryNotFound, PersistedQueryNotFoundMessage, "PERSISTED_QUERY_NOT_FOUND", batching)
+		}
+		op.Query = q
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(op.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return persistedQueryError(OutcomeInvalidPersistedQuery, PersistedQueryHashMismatchMessage, "", batching)
+	}
+
+	m.queryStore.Set(hash, op.Query)
+	return nil
+}
+
+// persistedQueryError is a requestError with the code Apollo clients expect
+// in the extensions of the error
+func persistedQueryError(outcome, message, code string, batching bool) error {
+	err := gqlerrors.NewFormattedError(message)
+	if code != "" {
+		err.Extensions = map[string]interface{}{"code": code}
+	}
+	return requestError{outcome: outcome, errors: []gqlerrors.FormattedError{err}, batching: batching}
+}
@@ -0,0 +1,140 @@
+package graphqlmultipart_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+const persistedQuery = "mutation($file:Upload) { upload(file: $file){ filename } }"
+
+func newPersistedQueryRequest(query, hash string) *http.Request {
+	return newFileUploadRequest(
+		map[string]string{
+			"operations": `{
+				"query":` + strconv.Quote(query) + `,
+				"variables":{"file":null},
+				"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}
+			}`,
+			"map": `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	)
+}
+
+func persistedQueryJSONError(message, code string) string {
+	ext := ""
+	if code != "" {
+		ext = `,"extensions":{"code":"` + code + `"}`
+	}
+	return `{"data":null,"errors":[{"message":"` + message + `","locations":[]` + ext + `}]}`
+}
+
+func TestPersistedQueries(t *testing.T) {
+	sum := sha256.Sum256([]byte(persistedQuery))
+	hash := hex.EncodeToString(sum[:])
+
+	mh := newPolicyHandler(graphqlmultipart.WithPersistedQueries(nil))
+	send := func(req *http.Request) string {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, req)
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		return string(body)
+	}
+
+	require.JSONEq(t,
+		persistedQueryJSONError(graphqlmultipart.PersistedQueryNotFoundMessage, "PERSISTED_QUERY_NOT_FOUND"),
+		send(newPersistedQueryRequest("", hash)),
+	)
+
+	require.JSONEq(t,
+		persistedQueryJSONError(graphqlmultipart.PersistedQueryHashMismatchMessage, ""),
+		send(newPersistedQueryRequest(persistedQuery, "0"+hash[1:])),
+	)
+
+	require.JSONEq(t,
+		`{"data":{"upload":{"filename":"handler.go"}}}`,
+		send(newPersistedQueryRequest(persistedQuery, hash)),
+	)
+
+	require.JSONEq(t,
+		`{"data":{"upload":{"filename":"handler.go"}}}`,
+		send(newPersistedQueryRequest("", hash)),
+	)
+}
+
+func TestPersistedQueries_NotSupported(t *testing.T) {
+	resp := httptest.NewRecorder()
+	newPolicyHandler().ServeHTTP(resp, newPersistedQueryRequest("", "abc"))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.JSONEq(t,
+		persistedQueryJSONError(graphqlmultipart.PersistedQueryNotSupportedMessage, "PERSISTED_QUERY_NOT_SUPPORTED"),
+		string(body),
+	)
+}
+
+func TestLRUQueryStore(t *testing.T) {
+	s := graphqlmultipart.NewLRUQueryStore(2)
+	s.Set("a", "query a")
+	s.Set("b", "query b")
+
+	q, ok := s.Get("a")
+	require.True(t, ok)
+	require.Equal(t, "query a", q)
+
+	s.Set("c", "query c")
+
+	_, ok = s.Get("b")
+	require.False(t, ok, "least recently used should have been evicted")
+
+	q, ok = s.Get("c")
+	require.True(t, ok)
+	require.Equal(t, "query c", q)
+}
+
+func TestLRUQueryStore_InvalidSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		s := graphqlmultipart.NewLRUQueryStore(size)
+		s.Set("a", "query a")
+
+		q, ok := s.Get("a")
+		require.True(t, ok, "should have used the default size")
+		require.Equal(t, "query a", q)
+	}
+}
+
+func TestPersistedQueries_MissBeforeFiles(t *testing.T) {
+	sum := sha256.Sum256([]byte(persistedQuery))
+	hash := hex.EncodeToString(sum[:])
+
+	mh := newPolicyHandler(
+		graphqlmultipart.WithPersistedQueries(nil),
+		graphqlmultipart.WithHooks(graphqlmultipart.Hooks{
+			OnFile: func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+				t.Errorf("file %s should not have been read", field)
+				return body, nil
+			},
+		}),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newPersistedQueryRequest("", hash))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.JSONEq(t,
+		persistedQueryJSONError(graphqlmultipart.PersistedQueryNotFoundMessage, "PERSISTED_QUERY_NOT_FOUND"),
+		string(body),
+	)
+}
@@ -0,0 +1,76 @@
+package graphqlmultipart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// WithDocumentCache keeps up to size parsed and validated documents in
+// memory, so repeated operations are executed without being parsed and
+// validated again. Documents are cached by the hash of the query and the
+// Schema they were validated against, and only the valid ones are kept. The
+// schemas replaced by a SchemaResolver are kept in memory while the cache has
+// documents of them.
+//
+// When it is enabled the operations are executed with graphql.Execute
+// instead of graphql.Do, so the parse and validation stages of the
+// extensions of the Schema are not called. A size of zero or less disables
+// the cache
+func WithDocumentCache(size int) Option {
+	return func(m *MultipartHandler) {
+		m.documents = nil
+		if size > 0 {
+			m.documents = newLRU(size)
+		}
+	}
+}
+
+// cachedDocument is a document validated against the schema, the entry keeps
+// the schema alive, so its address is not reused by another schema while the
+// document is in the cache
+type cachedDocument struct {
+	schema *graphql.Schema
+	doc    *ast.Document
+}
+
+// document parses and validates the query against the Schema, using the
+// cache of documents when it is enabled
+func (m MultipartHandler) document(query string) (*ast.Document, []gqlerrors.FormattedError) {
+	var key string
+	if m.documents != nil {
+		sum := sha256.Sum256([]byte(query))
+		key = fmt.Sprintf("%p:%s", m.Schema, hex.EncodeToString(sum[:]))
+
+		v, ok := m.documents.get(key)
+		c, _ := v.(cachedDocument)
+		ok = ok && c.schema == m.Schema
+		m.metrics.DocumentCacheLookup(ok)
+		if ok {
+			return c.doc, nil
+		}
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+
+	vr := graphql.ValidateDocument(m.Schema, doc, nil)
+	if !vr.IsValid {
+		return nil, vr.Errors
+	}
+
+	if m.documents != nil {
+		m.documents.set(key, cachedDocument{schema: m.Schema, doc: doc})
+	}
+	return doc, nil
+}
@@ -0,0 +1,150 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentCache(t *testing.T) {
+	pm := graphqlmultipart.NewPrometheusMetrics("test")
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(pm))
+
+	mh := newPolicyHandler(graphqlmultipart.WithDocumentCache(10), graphqlmultipart.WithMetrics(pm))
+
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go","size":`+handlerSize(t)+`}}}`, string(body))
+	}
+
+	// invalid documents are not cached
+	for i := 0; i < 2; i++ {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newFileUploadRequest(
+			map[string]string{
+				"operations": `{"query":"query($file:Upload) { upload(file: $file){ missing } }","variables":{"file":null}}`,
+				"map":        `{"file":["variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.Contains(t, string(body), `Cannot query field \"missing\"`)
+	}
+
+	expected := `
+		# HELP test_multipart_document_cache_lookups_total Number of lookups in the cache of parsed documents, by result (hit or miss)
+		# TYPE test_multipart_document_cache_lookups_total counter
+		test_multipart_document_cache_lookups_total{result="hit"} 2
+		test_multipart_document_cache_lookups_total{result="miss"} 3
+	`
+	require.NoError(t, promtestutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"test_multipart_document_cache_lookups_total",
+	))
+}
+
+func TestDocumentCache_Disabled(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		pm := graphqlmultipart.NewPrometheusMetrics("test")
+		reg := prometheus.NewPedanticRegistry()
+		require.NoError(t, reg.Register(pm))
+
+		mh := newPolicyHandler(graphqlmultipart.WithDocumentCache(size), graphqlmultipart.WithMetrics(pm))
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go","size":`+handlerSize(t)+`}}}`, string(body))
+
+		n, err := promtestutil.GatherAndCount(reg, "test_multipart_document_cache_lookups_total")
+		require.NoError(t, err)
+		require.Equal(t, 0, n, "the cache should have been disabled")
+	}
+}
+
+func BenchmarkDocumentCache(b *testing.B) {
+	r := testutil.NewGraphQLFileUploadRequest(
+		"/graphql",
+		map[string]string{
+			"operations": `{
+				"query":"mutation Upload($input: SpecialUploadInput, $file: Upload) { specialUploads(input: $input) { filename, size, headers { name, values } } upload(file: $file) { filename, size, headers { name, values } } }",
+				"variables":{"input":{"name":"a","files":[null,null]},"file":null}
+			}`,
+			"map": `{"file":["variables.input.files.0","variables.input.files.1","variables.file"]}`,
+		},
+		map[string]string{"file": "README.md"},
+	)
+	contentType := r.Header.Get("Content-Type")
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(b, err)
+
+	cases := map[string][]graphqlmultipart.Option{
+		"without_cache": nil,
+		"with_cache":    {graphqlmultipart.WithDocumentCache(100)},
+	}
+
+	for name, opts := range cases {
+		b.Run(name, func(b *testing.B) {
+			mh := newPolicyHandler(opts...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req, _ := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+				req.Header.Set("Content-Type", contentType)
+				resp := httptest.NewRecorder()
+				mh.ServeHTTP(resp, req)
+				if !bytes.Contains(resp.Body.Bytes(), []byte(`"data":{`)) {
+					b.Fatal(resp.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentCache_EarlyValidation(t *testing.T) {
+	pm := graphqlmultipart.NewPrometheusMetrics("test")
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(pm))
+
+	mh := newPolicyHandler(
+		graphqlmultipart.WithDocumentCache(10),
+		graphqlmultipart.WithEarlyValidation(),
+		graphqlmultipart.WithMetrics(pm),
+	)
+
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go","size":`+handlerSize(t)+`}}}`, string(body))
+	}
+
+	// the document validated before the files is the one executed, so it is
+	// looked up once by request
+	expected := `
+		# HELP test_multipart_document_cache_lookups_total Number of lookups in the cache of parsed documents, by result (hit or miss)
+		# TYPE test_multipart_document_cache_lookups_total counter
+		test_multipart_document_cache_lookups_total{result="hit"} 2
+		test_multipart_document_cache_lookups_total{result="miss"} 1
+	`
+	require.NoError(t, promtestutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"test_multipart_document_cache_lookups_total",
+	))
+}
@@ -0,0 +1,188 @@
+package graphqlmultipart
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+var (
+	// InvalidChecksumsFieldMessage is shown when the checksums field format is invalid
+	InvalidChecksumsFieldMessage = "Field \"checksums\" format is not valid, it must be a object of the files to their digests (sha-256=<base64>,md5=<base64>)"
+
+	// InvalidChecksumMessage is shown when the checksum informed for a file is not valid
+	InvalidChecksumMessage = "Checksum sent for file \"%[1]s\" is not valid"
+
+	// ChecksumMismatchMessage is shown when the content received for a file does not match its checksum
+	ChecksumMismatchMessage = "File \"%[1]s\" does not match the %[2]s checksum sent, it may have been truncated or corrupted"
+)
+
+// WithChecksumVerification makes the handler verify the files against the
+// digests sent by the client, using the "Content-MD5" or "Digest" headers of
+// each part or a "checksums" field with the same keys as the "map" and the
+// digests as value (e.g.: {"0":"sha-256=<base64>"})
+func WithChecksumVerification() Option {
+	return func(m *MultipartHandler) {
+		m.verifyChecksums = true
+	}
+}
+
+var digestAlgorithms = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha-256": sha256.New,
+}
+
+// digests are the expected sums of a file, by algorithm
+type digests map[string][]byte
+
+// checksumError is a invalid or mismatched checksum, without a field it means
+// the "checksums" field is invalid
+type checksumError struct {
+	field     string
+	algorithm string
+}
+
+func (e checksumError) Error() string {
+	if e.field == "" {
+		return InvalidChecksumsFieldMessage
+	}
+
+	if e.algorithm == "" {
+		return fmt.Sprintf(InvalidChecksumMessage, e.field)
+	}
+
+	return fmt.Sprintf(ChecksumMismatchMessage, e.field, e.algorithm)
+}
+
+// parseDigests reads digests in the format of the "Digest" header:
+// sha-256=<base64>,md5=<base64>; unknown algorithms are ignored
+func parseDigests(s string, ds digests) bool {
+	for _, d := range strings.Split(s, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+
+		i := strings.Index(d, "=")
+		if i == -1 {
+			return false
+		}
+
+		alg := strings.ToLower(d[:i])
+		if _, ok := digestAlgorithms[alg]; !ok {
+			continue
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(d[i+1:])
+		if err != nil {
+			return false
+		}
+		ds[alg] = sum
+	}
+
+	return true
+}
+
+// partDigests reads the digests from the "Content-MD5" and "Digest" headers
+func partDigests(field string, h textproto.MIMEHeader) (digests, error) {
+	ds := make(digests)
+	if v := h.Get("Content-MD5"); v != "" {
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			return nil, checksumError{field: field}
+		}
+		ds["md5"] = sum
+	}
+
+	for _, v := range h["Digest"] {
+		if !parseDigests(v, ds) {
+			return nil, checksumError{field: field}
+		}
+	}
+
+	return ds, nil
+}
+
+// parseChecksumsField reads the "checksums" field
+func parseChecksumsField(v string) (map[string]digests, error) {
+	fs := make(map[string]string)
+	if err := json.Unmarshal([]byte(v), &fs); err != nil {
+		return nil, checksumError{}
+	}
+
+	cs := make(map[string]digests, len(fs))
+	for f, v := range fs {
+		ds := make(digests)
+		if !parseDigests(v, ds) {
+			return nil, checksumError{field: f}
+		}
+		cs[f] = ds
+	}
+
+	return cs, nil
+}
+
+func (ds digests) verify(field string, sums digests) error {
+	for alg, sum := range ds {
+		if !bytes.Equal(sum, sums[alg]) {
+			return checksumError{field: field, algorithm: alg}
+		}
+	}
+	return nil
+}
+
+// checksumReader calculates the digests of the content while it is read,
+// failing at the end of it if they don't match the expected ones
+type checksumReader struct {
+	r        io.Reader
+	field    string
+	expected digests
+	hashes   map[string]hash.Hash
+	sums     digests
+	err      error
+}
+
+func newChecksumReader(field string, r io.Reader, expected digests) *checksumReader {
+	hs := make(map[string]hash.Hash, len(digestAlgorithms))
+	for alg, h := range digestAlgorithms {
+		hs[alg] = h()
+	}
+
+	return &checksumReader{
+		r:        r,
+		field:    field,
+		expected: expected,
+		hashes:   hs,
+	}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.r.Read(p)
+	for _, h := range c.hashes {
+		h.Write(p[:n])
+	}
+
+	if err == io.EOF {
+		c.sums = make(digests, len(c.hashes))
+		for alg, h := range c.hashes {
+			c.sums[alg] = h.Sum(nil)
+		}
+
+		if c.err = c.expected.verify(c.field, c.sums); c.err != nil {
+			return n, c.err
+		}
+	}
+
+	return n, err
+}
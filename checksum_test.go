@@ -0,0 +1,127 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newChecksumUploadRequest(content []byte, fileHeader map[string]string, checksums string, checksumsAfter bool) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	w.WriteField("operations", `{
+		"query":"query($file:Upload) { upload(file: $file){ size } }",
+		"variables":{"file":null}
+	}`)
+	w.WriteField("map", `{"file":["variables.file"]}`)
+	if checksums != "" && !checksumsAfter {
+		w.WriteField("checksums", checksums)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="file.txt"`)
+	for k, v := range fileHeader {
+		h.Set(k, v)
+	}
+	p, _ := w.CreatePart(h)
+	p.Write(content)
+
+	if checksums != "" && checksumsAfter {
+		w.WriteField("checksums", checksums)
+	}
+	w.Close()
+
+	r, _ := http.NewRequest("POST", "/graphql", body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestChecksumVerification(t *testing.T) {
+	content := []byte("some content that must arrive complete")
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	b64MD5 := base64.StdEncoding.EncodeToString(md5Sum[:])
+	b64SHA256 := base64.StdEncoding.EncodeToString(sha256Sum[:])
+
+	truncated := content[:10]
+	ok := fmt.Sprintf(`{"data":{"upload":{"size":%d}}}`, len(content))
+
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+	cases := map[string]test{
+		"no_checksums": test{
+			req:   newChecksumUploadRequest(content, nil, "", false),
+			respo: ok,
+		},
+		"content_md5": test{
+			req:   newChecksumUploadRequest(content, map[string]string{"Content-MD5": b64MD5}, "", false),
+			respo: ok,
+		},
+		"content_md5_mismatch": test{
+			req:   newChecksumUploadRequest(truncated, map[string]string{"Content-MD5": b64MD5}, "", false),
+			respo: getJSONError(graphqlmultipart.ChecksumMismatchMessage, "file", "md5"),
+		},
+		"digest": test{
+			req:   newChecksumUploadRequest(content, map[string]string{"Digest": "SHA-256=" + b64SHA256 + ",unknown=abc"}, "", false),
+			respo: ok,
+		},
+		"digest_mismatch": test{
+			req:   newChecksumUploadRequest(truncated, map[string]string{"Digest": "sha-256=" + b64SHA256}, "", false),
+			respo: getJSONError(graphqlmultipart.ChecksumMismatchMessage, "file", "sha-256"),
+		},
+		"invalid_digest": test{
+			req:   newChecksumUploadRequest(content, map[string]string{"Digest": "sha-256=???"}, "", false),
+			respo: getJSONError(graphqlmultipart.InvalidChecksumMessage, "file"),
+		},
+		"checksums_field": test{
+			req:   newChecksumUploadRequest(content, nil, `{"file":"sha-256=`+b64SHA256+`"}`, false),
+			respo: ok,
+		},
+		"checksums_field_mismatch": test{
+			req:   newChecksumUploadRequest(truncated, nil, `{"file":"md5=`+b64MD5+`"}`, false),
+			respo: getJSONError(graphqlmultipart.ChecksumMismatchMessage, "file", "md5"),
+		},
+		"checksums_field_after_files_mismatch": test{
+			req:   newChecksumUploadRequest(truncated, nil, `{"file":"md5=`+b64MD5+`"}`, true),
+			respo: getJSONError(graphqlmultipart.ChecksumMismatchMessage, "file", "md5"),
+		},
+		"invalid_checksums_field": test{
+			req:   newChecksumUploadRequest(content, nil, `["md5"]`, false),
+			respo: getJSONError(graphqlmultipart.InvalidChecksumsFieldMessage),
+		},
+	}
+
+	mh := graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithChecksumVerification(),
+	)
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
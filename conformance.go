@@ -0,0 +1,150 @@
+package graphqlmultipart
+
+import (
+	"fmt"
+	"mime/multipart"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// ConformanceMode sets what the handler does when a request does not conform
+// to the spec in ways that don't stop it from being executed
+type ConformanceMode int
+
+const (
+	// ConformanceIgnore accepts the requests silently, it's the default
+	ConformanceIgnore ConformanceMode = iota
+	// ConformanceWarn executes the request, but reports the problems as
+	// "warnings" in the extensions of the results
+	ConformanceWarn
+	// ConformanceStrict rejects the request, reporting all the problems found
+	ConformanceStrict
+)
+
+var (
+	// DuplicatePartMessage is shown when a field of the form is sent more than once
+	DuplicatePartMessage = fmt.Sprintf("Field \"%%[1]s\" was sent %%[2]d times, but it must be sent only once (%s)", specURL)
+
+	// UnusedFileMessage is shown when a file is sent, but is not in the map
+	UnusedFileMessage = fmt.Sprintf("File \"%%[1]s\" was sent, but is not used by the map (%s)", specURL)
+
+	// ConflictingMapPathMessage is shown when more than one file, or the same
+	// file more than once, is mapped to the same path
+	ConflictingMapPathMessage = fmt.Sprintf("Mapping path \"%%[1]s\" is used by files %%[2]s, but only one file can be mapped to a path (%s)", specURL)
+)
+
+// warning is a problem of the request reported in the extensions
+type warning struct {
+	Message string `json:"message"`
+}
+
+// WithConformance sets what the handler does with requests that have
+// duplicated fields, files that are not in the map or paths mapped to more
+// than one file
+func WithConformance(mode ConformanceMode) Option {
+	return func(m *MultipartHandler) {
+		m.conformance = mode
+	}
+}
+
+// conformanceProblems lists the problems of the request, in a stable order.
+// The values of HTML forms are not checked for duplicates, as lists receive
+// all the values of a field
+func conformanceProblems(form *multipart.Form, req *request, htmlForm bool) []string {
+	problems := make([]string, 0)
+
+	duplicated := make([]string, 0)
+	for n, vs := range form.Value {
+		if len(vs) > 1 && !htmlForm {
+			duplicated = append(duplicated, n)
+		}
+	}
+	for n, fhs := range form.File {
+		if len(fhs) > 1 {
+			duplicated = append(duplicated, n)
+		}
+	}
+	sort.Strings(duplicated)
+	for _, n := range duplicated {
+		problems = append(problems, fmt.Sprintf(DuplicatePartMessage, n, len(form.Value[n])+len(form.File[n])))
+	}
+
+	unused := make([]string, 0)
+	for n := range form.File {
+		if _, ok := req.fileMap[n]; !ok {
+			unused = append(unused, n)
+		}
+	}
+	sort.Strings(unused)
+	for _, n := range unused {
+		problems = append(problems, fmt.Sprintf(UnusedFileMessage, n))
+	}
+
+	// paths are compared by their segments, so the same value written in
+	// different syntaxes is still a conflict
+	files := make(map[string][]string)
+	raws := make(map[string]string)
+	for f, ps := range req.fileMap {
+		for _, p := range ps {
+			files[p.key()] = append(files[p.key()], f)
+			if r, ok := raws[p.key()]; !ok || p.raw < r {
+				raws[p.key()] = p.raw
+			}
+		}
+	}
+
+	conflicts := make([]string, 0)
+	for k, fs := range files {
+		if len(fs) > 1 {
+			conflicts = append(conflicts, k)
+		}
+	}
+	sort.Strings(conflicts)
+	for _, k := range conflicts {
+		fs := files[k]
+		sort.Strings(fs)
+		problems = append(problems, fmt.Sprintf(ConflictingMapPathMessage, raws[k], strings.Join(fs, ", ")))
+	}
+
+	return problems
+}
+
+// checkConformance returns the error for the problems of the request when
+// the mode is strict, and the warnings to be reported otherwise
+func (m MultipartHandler) checkConformance(form *multipart.Form, req *request) ([]warning, error) {
+	if m.conformance == ConformanceIgnore {
+		return nil, nil
+	}
+
+	problems := conformanceProblems(form, req, m.htmlForm != nil)
+	if len(problems) == 0 {
+		return nil, nil
+	}
+
+	if m.conformance == ConformanceStrict {
+		errs := make([]gqlerrors.FormattedError, len(problems))
+		for i, p := range problems {
+			errs[i] = gqlerrors.NewFormattedError(p)
+		}
+		return nil, requestError{outcome: OutcomeNonConformant, errors: errs, batching: req.batching}
+	}
+
+	ws := make([]warning, len(problems))
+	for i, p := range problems {
+		ws[i] = warning{Message: p}
+	}
+	return ws, nil
+}
+
+// addWarnings reports the warnings in the extensions of every result
+func addWarnings(results []*graphql.Result, ws []warning) {
+	for _, res := range results {
+		if res.Extensions == nil {
+			res.Extensions = make(map[string]interface{})
+		}
+		res.Extensions["warnings"] = ws
+	}
+}
@@ -0,0 +1,89 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newNonConformantRequest() *http.Request {
+	ops := `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}`
+
+	buf := new(bytes.Buffer)
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("operations", ops)
+	mw.WriteField("operations", ops)
+	mw.WriteField("map", `{"a":["variables.file"],"b":["variables.file"]}`)
+	for _, n := range []string{"a", "b", "c"} {
+		p, _ := mw.CreateFormFile(n, "handler.go")
+		p.Write([]byte("content"))
+	}
+	mw.Close()
+
+	r, _ := http.NewRequest("POST", "/graphql", buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestConformance(t *testing.T) {
+	problems := []string{
+		fmt.Sprintf(graphqlmultipart.DuplicatePartMessage, "operations", 2),
+		fmt.Sprintf(graphqlmultipart.UnusedFileMessage, "c"),
+		fmt.Sprintf(graphqlmultipart.ConflictingMapPathMessage, "variables.file", "a, b"),
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		newPolicyHandler(graphqlmultipart.WithConformance(graphqlmultipart.ConformanceStrict)).
+			ServeHTTP(resp, newNonConformantRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `{"data":null,"errors":[
+			{"message":`+strconv.Quote(problems[0])+`,"locations":[]},
+			{"message":`+strconv.Quote(problems[1])+`,"locations":[]},
+			{"message":`+strconv.Quote(problems[2])+`,"locations":[]}
+		]}`, string(body))
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		newPolicyHandler(graphqlmultipart.WithConformance(graphqlmultipart.ConformanceWarn)).
+			ServeHTTP(resp, newNonConformantRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `{
+			"data":{"upload":{"filename":"handler.go"}},
+			"extensions":{"warnings":[
+				{"message":`+strconv.Quote(problems[0])+`},
+				{"message":`+strconv.Quote(problems[1])+`},
+				{"message":`+strconv.Quote(problems[2])+`}
+			]}
+		}`, string(body))
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		newPolicyHandler().ServeHTTP(resp, newNonConformantRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go"}}}`, string(body))
+	})
+
+	t.Run("conformant", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		newPolicyHandler(graphqlmultipart.WithConformance(graphqlmultipart.ConformanceStrict)).
+			ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go","size":`+handlerSize(t)+`}}}`, string(body))
+	})
+}
@@ -0,0 +1,51 @@
+package graphqlmultipart
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CSRFPreventionMessage is shown when a request doesn't have any of the headers
+// required by the CSRF prevention
+var CSRFPreventionMessage = "This request has been blocked as a possible Cross-Site Request Forgery (CSRF), it must have a non-empty value for one of the headers: %[1]s"
+
+// DefaultCSRFPreventionHeaders are the headers accepted by WithCSRFPrevention
+// when none is informed, the same ones used by Apollo
+var DefaultCSRFPreventionHeaders = []string{"X-Apollo-Operation-Name", "Apollo-Require-Preflight"}
+
+// WithCSRFPrevention makes the handler reject the multipart requests without a
+// non-empty value for at least one of the headers informed, as
+// "multipart/form-data" requests can be sent cross-site by browsers without a
+// preflight, requiring a header that can't be sent this way prevents them.
+// Handlers with WithHTMLForm check the origin of the requests instead, as
+// plain HTML forms can't send headers
+func WithCSRFPrevention(headers ...string) Option {
+	if len(headers) == 0 {
+		headers = DefaultCSRFPreventionHeaders
+	}
+
+	return func(m *MultipartHandler) {
+		m.csrfHeaders = headers
+	}
+}
+
+// preventCSRF returns a error if the request doesn't have the headers required,
+// or for HTML forms, if it was not sent from a origin allowed
+func (m MultipartHandler) preventCSRF(r *http.Request) error {
+	if m.htmlForm != nil {
+		return m.htmlForm.checkOrigin(r)
+	}
+
+	if len(m.csrfHeaders) == 0 {
+		return nil
+	}
+
+	for _, h := range m.csrfHeaders {
+		if strings.TrimSpace(r.Header.Get(h)) != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(CSRFPreventionMessage, strings.Join(m.csrfHeaders, ", "))
+}
@@ -0,0 +1,88 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFPrevention(t *testing.T) {
+	blocked := getJSONError(
+		graphqlmultipart.CSRFPreventionMessage,
+		strings.Join(graphqlmultipart.DefaultCSRFPreventionHeaders, ", "),
+	)
+	ok := `{"data":{"upload":{"filename":"handler.go"}}}`
+
+	cases := map[string]struct {
+		headers map[string]string
+		respo   string
+	}{
+		"no_headers":        {respo: blocked},
+		"empty_header":      {headers: map[string]string{"Apollo-Require-Preflight": " "}, respo: blocked},
+		"require_preflight": {headers: map[string]string{"Apollo-Require-Preflight": "true"}, respo: ok},
+		"operation_name":    {headers: map[string]string{"X-Apollo-Operation-Name": "Upload"}, respo: ok},
+	}
+
+	mh := graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("reached me"))
+		}),
+		graphqlmultipart.WithCSRFPrevention(),
+	)
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}`,
+					"map":        `{"file":["variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, r)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+
+	t.Run("other_content_types_are_forwarded", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/graphql", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, r)
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.Equal(t, "reached me", string(body))
+	})
+
+	t.Run("custom_headers", func(t *testing.T) {
+		mh := graphqlmultipart.NewHandler(
+			&testutil.Schema,
+			1*1024,
+			nil,
+			graphqlmultipart.WithCSRFPrevention("X-Requested-With"),
+		)
+
+		r := newSimpleUploadRequest()
+		r.Header.Set("Apollo-Require-Preflight", "true")
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, r)
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, getJSONError(graphqlmultipart.CSRFPreventionMessage, "X-Requested-With"), string(body))
+	})
+}
@@ -0,0 +1,125 @@
+package graphqlmultipart_test
+
+import (
+	"context"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newAttachmentSchema(t *testing.T) *graphql.Schema {
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"attachment": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						ext, ok := graphqlmultipart.ExtensionsFromContext(p.Context)
+						if !ok {
+							return nil, nil
+						}
+						return ext["attachment"].(*multipart.FileHeader).Filename, nil
+					},
+				},
+			},
+		}),
+	})
+	require.NoError(t, err)
+	return &s
+}
+
+func TestExtensions(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	cases := map[string]test{
+		"single": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"{ attachment }","variables":{},"extensions":{"attachment":null}}`,
+					"map":        `{"file":["extensions.attachment"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `{"data":{"attachment":"handler.go"}}`,
+		},
+		"batching": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"query":"{ attachment }","variables":{},"extensions":{"attachment":null}},
+						{"query":"{ attachment }","variables":{}}
+					]`,
+					"map": `{"file":["0.extensions.attachment"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `[{"data":{"attachment":"handler.go"}},{"data":{"attachment":null}}]`,
+		},
+		"missing_extension": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"{ attachment }","variables":{}}`,
+					"map":        `{"file":["extensions.attachment"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "extensions.attachment", "file"),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			graphqlmultipart.NewHandler(
+				newAttachmentSchema(t),
+				1*1024,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("should not have forwarded the request"))
+				}),
+				graphqlmultipart.WithEarlyValidation(),
+			).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestExtensions_OnOperation(t *testing.T) {
+	var received graphqlmultipart.Operation
+	mh := graphqlmultipart.NewHandler(
+		newAttachmentSchema(t),
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithHooks(graphqlmultipart.Hooks{
+			OnOperation: func(ctx context.Context, op graphqlmultipart.Operation) error {
+				received = op
+				return nil
+			},
+		}),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `{"query":"{ attachment }","variables":{},"extensions":{"attachment":null,"persistedQuery":{"version":1}}}`,
+			"map":        `{"file":["extensions.attachment"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+
+	require.Equal(t, "handler.go", received.Extensions["attachment"].(*multipart.FileHeader).Filename)
+	require.Equal(t, map[string]interface{}{"version": float64(1)}, received.Extensions["persistedQuery"])
+}
@@ -0,0 +1,355 @@
+package graphqlmultipart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// maxValueBytes is the limit of bytes read for the non-file parts, the same
+// used by the http.Request.ParseMultipartForm
+const maxValueBytes = int64(10 << 20)
+
+const (
+	// maxParts is the limit of parts of a form, the same default of the
+	// multipart.Reader.ReadForm
+	maxParts = 1000
+
+	// maxHeaders is the limit of headers of all the parts together, the same
+	// default of the multipart.Reader.ReadForm
+	maxHeaders = 10000
+)
+
+// readForm reads the multipart body part by part, so each file can be handled
+// as it arrives, filling r.MultipartForm as ParseMultipartForm would do.
+// If beforeFiles is informed it's called before the first file is read, or
+// after the last part when there are no files; as the spec requires the
+// "operations" and "map" fields to be sent first they will be available
+func (m MultipartHandler) readForm(ctx context.Context, r *http.Request, beforeFiles func(*multipart.Form) error) (form *multipart.Form, err error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	form = &multipart.Form{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*multipart.FileHeader),
+	}
+	r.MultipartForm = form
+
+	var files *fileStore
+	defer func() {
+		if err != nil && files != nil {
+			files.abort(err)
+		}
+	}()
+
+	// legacy forms without the "map" field can only be checked after the
+	// last file, when it is known which fields have files
+	legacy := false
+
+	valueBytes := maxValueBytes
+	parts, headers := 0, 0
+	sums := make(map[string]digests)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parts++
+		for _, vs := range p.Header {
+			headers += len(vs)
+		}
+		if parts > maxParts || headers > maxHeaders {
+			return nil, multipart.ErrMessageTooLarge
+		}
+
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+
+		if p.FileName() == "" {
+			var b bytes.Buffer
+			n, err := io.CopyN(&b, p, valueBytes+1)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			valueBytes -= n
+			if valueBytes < 0 {
+				return nil, multipart.ErrMessageTooLarge
+			}
+			if m.htmlForm != nil && (name == "operations" || name == "map") {
+				return nil, requestError{outcome: OutcomeInvalidOperationsField, message: fmt.Sprintf(HTMLFormFieldMessage, name)}
+			}
+
+			form.Value[name] = append(form.Value[name], b.String())
+			continue
+		}
+
+		if m.legacyFormats && !legacy && isLegacyForm(form) {
+			if hasFields(form, "map") {
+				normalizeLegacyForm(form)
+			} else {
+				legacy = true
+			}
+		}
+
+		// the map of HTML forms is only known after the last file too
+		if beforeFiles != nil && !legacy && m.htmlForm == nil {
+			if !hasFields(form, "operations", "map") {
+				return nil, requestError{outcome: OutcomeFilesBeforeOperations, message: FilesBeforeOperationsMessage}
+			}
+
+			if err := beforeFiles(form); err != nil {
+				return nil, err
+			}
+			beforeFiles = nil
+		}
+
+		if files == nil {
+			files = newFileStore(m.maxMemory)
+		}
+
+		sum, err := m.readFile(ctx, p, files)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := sums[name]; !ok {
+			sums[name] = sum
+		}
+	}
+
+	if files != nil {
+		stored, err := files.close()
+		files = nil
+		if err != nil {
+			return nil, err
+		}
+		form.File = stored.File
+	}
+
+	if beforeFiles != nil {
+		if legacy && hasFields(form, "map") {
+			form.RemoveAll()
+			return nil, requestError{outcome: OutcomeFilesBeforeOperations, message: FilesBeforeOperationsMessage}
+		}
+
+		if err := beforeFiles(form); err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+	}
+
+	if vs, ok := form.Value["checksums"]; ok && m.verifyChecksums {
+		cs, err := parseChecksumsField(vs[0])
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+
+		for f, expected := range cs {
+			if sum, ok := sums[f]; ok {
+				if err := expected.verify(f, sum); err != nil {
+					form.RemoveAll()
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return form, nil
+}
+
+// readFile consumes a file part into the fileStore
+func (m MultipartHandler) readFile(ctx context.Context, p *multipart.Part, files *fileStore) (digests, error) {
+	ctx, span := m.tracer.Start(ctx, "graphqlmultipart.file")
+	defer span.End()
+
+	span.SetAttributes(
+		attrFieldName.String(p.FormName()),
+		attrFileName.String(p.FileName()),
+	)
+
+	var body io.Reader = p
+
+	var checksum *checksumReader
+	if m.verifyChecksums {
+		expected, err := partDigests(p.FormName(), p.Header)
+		if err != nil {
+			recordError(span, OutcomeInvalidChecksum, err)
+			return nil, err
+		}
+
+		checksum = newChecksumReader(p.FormName(), body, expected)
+		body = checksum
+	}
+
+	if m.Hooks.OnFile != nil {
+		var err error
+		body, err = m.Hooks.OnFile(ctx, p.FormName(), p.Header, body)
+		if err != nil {
+			recordError(span, OutcomeRejected, err)
+			return nil, rejectedError{err: err}
+		}
+	}
+
+	header := make(textproto.MIMEHeader, len(p.Header))
+	for k, vs := range p.Header {
+		header[k] = append([]string(nil), vs...)
+	}
+
+	for _, t := range m.transformers {
+		var err error
+		body, err = t(header, body)
+		if err != nil {
+			recordError(span, OutcomeRejected, err)
+			return nil, rejectedError{err: err}
+		}
+
+		if c, ok := body.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+
+	size, err := files.add(p.FormName(), header, body)
+	if err == nil && checksum != nil && checksum.sums == nil {
+		// the transformers may not read the part until its end
+		_, err = io.Copy(ioutil.Discard, checksum)
+	}
+	if checksum != nil && checksum.err != nil {
+		err = checksum.err
+	}
+	if err != nil {
+		recordError(span, errorOutcome(err), err)
+		return nil, err
+	}
+
+	span.SetAttributes(attrFileSize.Int64(size))
+	m.metrics.FileReceived(size)
+
+	if checksum != nil {
+		return checksum.sums, nil
+	}
+	return nil, nil
+}
+
+// fileStore stores the file parts as a *multipart.FileHeader, as they can only
+// be created by the multipart package the parts are re-encoded into a single
+// form read by a multipart.Reader.ReadForm, keeping up to maxMemory bytes of
+// all the files in memory and the rest in temporary files
+type fileStore struct {
+	mw   *multipart.Writer
+	pw   *io.PipeWriter
+	done chan struct{}
+	form *multipart.Form
+	err  error
+
+	// the transformers may change the headers after the part is written,
+	// so they are set into the *multipart.FileHeader at the end
+	headers map[string][]textproto.MIMEHeader
+}
+
+func newFileStore(maxMemory int64) *fileStore {
+	pr, pw := io.Pipe()
+	s := &fileStore{
+		mw:      multipart.NewWriter(pw),
+		pw:      pw,
+		done:    make(chan struct{}),
+		headers: make(map[string][]textproto.MIMEHeader),
+	}
+
+	go func() {
+		defer close(s.done)
+		s.form, s.err = multipart.NewReader(pr, s.mw.Boundary()).ReadForm(maxMemory)
+		pr.CloseWithError(s.err)
+	}()
+
+	return s
+}
+
+// add stores the content of a file part of the field with the header,
+// returning its size
+func (s *fileStore) add(name string, header textproto.MIMEHeader, body io.Reader) (int64, error) {
+	w, err := s.mw.CreatePart(header)
+	if err != nil {
+		return 0, err
+	}
+	s.headers[name] = append(s.headers[name], header)
+	return io.Copy(w, body)
+}
+
+// close finishes the form and returns the files stored
+func (s *fileStore) close() (*multipart.Form, error) {
+	err := s.mw.Close()
+	s.pw.CloseWithError(err)
+	<-s.done
+	if err == nil {
+		err = s.err
+	}
+	if err != nil {
+		if s.form != nil {
+			s.form.RemoveAll()
+		}
+		return nil, err
+	}
+
+	for name, fhs := range s.form.File {
+		for i, fh := range fhs {
+			fh.Header = s.headers[name][i]
+		}
+	}
+	return s.form, nil
+}
+
+// abort discards the files stored
+func (s *fileStore) abort(err error) {
+	s.pw.CloseWithError(err)
+	<-s.done
+	if s.form != nil {
+		s.form.RemoveAll()
+	}
+}
+
+func hasFields(form *multipart.Form, names ...string) bool {
+	for _, n := range names {
+		if _, ok := form.Value[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// errorOutcome classifies the errors returned by readForm and parseRequest
+func errorOutcome(err error) string {
+	var reqErr requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.outcome
+	}
+
+	var rejected rejectedError
+	if errors.As(err, &rejected) {
+		return OutcomeRejected
+	}
+
+	var checksum checksumError
+	if !errors.As(err, &checksum) {
+		return OutcomeFailedToParseForm
+	}
+
+	if checksum.field == "" || checksum.algorithm == "" {
+		return OutcomeInvalidChecksum
+	}
+	return OutcomeChecksumMismatch
+}
@@ -6,16 +6,25 @@
 package graphqlmultipart
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const specURL = "https://github.com/jaydenseric/graphql-multipart-request-spec/tree/v2.0.0"
@@ -46,27 +55,75 @@ var (
 // MultipartHandler implements the specification for handling multipart/form-data
 // see more at: https://github.com/jaydenseric/graphql-multipart-request-spec/tree/v2.0.0
 type MultipartHandler struct {
-	Schema    *graphql.Schema
-	next      http.Handler
-	maxMemory int64
+	Schema     *graphql.Schema
+	Hooks      Hooks
+	next       http.Handler
+	maxMemory  int64
+	metrics    Metrics
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	transformers     []Transformer
+	verifyChecksums  bool
+	batchConcurrency int
+
+	disableBatching         bool
+	maxBatchSize            int
+	uploadsOnlyForMutations bool
+
+	csrfHeaders []string
+
+	earlyValidation bool
+	strictUploads   bool
+	conformance     ConformanceMode
+	queryStore      QueryStore
+
+	trustedDocuments *TrustedDocuments
+	documents        *lru
+	limits           Limits
+
+	schemaResolver SchemaResolver
+	legacyFormats  bool
+	htmlForm       *HTMLForm
+}
+
+// Option configures optional behaviours of the MultipartHandler
+type Option func(*MultipartHandler)
+
+// WithBatchConcurrency makes the operations of a batched request run
+// concurrently, up to n at a time. The results keep the order of the
+// operations, but the Hooks.OnOperation may be called concurrently
+func WithBatchConcurrency(n int) Option {
+	return func(m *MultipartHandler) {
+		m.batchConcurrency = n
+	}
 }
 
 // NewHandler wraps the default GraphQL handler within a MultipartHandler, if it
 // receives a request that is not "multipart/form-data", it will be forwarded to
 // the wrapped handler
-func NewHandler(s *graphql.Schema, maxMemory int64, next http.Handler) http.Handler {
-	return MultipartHandler{
-		Schema:    s,
-		maxMemory: maxMemory,
-		next:      next,
+func NewHandler(s *graphql.Schema, maxMemory int64, next http.Handler, opts ...Option) http.Handler {
+	m := MultipartHandler{
+		Schema:     s,
+		maxMemory:  maxMemory,
+		next:       next,
+		metrics:    noopMetrics{},
+		tracer:     defaultTracer(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+
+	for _, opt := range opts {
+		opt(&m)
 	}
+
+	return m
 }
 
 // NewMiddlewareWrapper retrieves a func to help wrap multiple GraphQL handler with
 // the MultipartHandler
-func NewMiddlewareWrapper(s *graphql.Schema, maxMemory int64) func(next http.Handler) http.Handler {
+func NewMiddlewareWrapper(s *graphql.Schema, maxMemory int64, opts ...Option) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return NewHandler(s, maxMemory, next)
+		return NewHandler(s, maxMemory, next, opts...)
 	}
 }
 
@@ -74,7 +131,14 @@ type operationField struct {
 	Query         string                  `json:"query"`
 	Variables     *map[string]interface{} `json:"variables"`
 	OperationName string                  `json:"operationName"`
-	mapPrefix     string
+	Extensions    map[string]interface{}  `json:"extensions"`
+	DocumentID    string                  `json:"documentId"`
+	mapPrefix     []string
+
+	// doc and def are set when the operation was validated before the
+	// files, so it is not parsed and validated again
+	doc *ast.Document
+	def *ast.OperationDefinition
 }
 
 // ServeHTTP will process requests of the type "multipart/form-data", if other
@@ -87,63 +151,170 @@ func (m MultipartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(m.maxMemory); err != nil {
-		log.Printf("[MultipartHandler] Fail do parse multipart form: %s", err.Error())
-		writeError(w, FailedToParseFormMessage)
-		return
+	// a MultipartHandler created without NewHandler has none of them set
+	if m.metrics == nil {
+		m.metrics = noopMetrics{}
+	}
+	if m.tracer == nil {
+		m.tracer = defaultTracer()
+	}
+	if m.propagator == nil {
+		m.propagator = otel.GetTextMapPropagator()
 	}
 
-	form := r.MultipartForm
+	m.metrics.UploadStarted()
+	defer m.metrics.UploadFinished()
 
-	var vs []string
-	var ok bool
+	ctx := m.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := m.tracer.Start(ctx, "graphqlmultipart.request", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
 
-	if vs, ok = form.Value["operations"]; !ok {
-		writeError(w, OperationsFieldMissingMessage)
+	if err := m.preventCSRF(r); err != nil {
+		m.fail(w, span, OutcomeCSRFPrevented, err.Error())
 		return
 	}
-	opsStr := vs[0]
 
-	if vs, ok = form.Value["map"]; !ok {
-		writeError(w, MapFieldMissingMessage)
-		return
+	if m.Hooks.Authorize != nil {
+		if err := m.Hooks.Authorize(r); err != nil {
+			m.fail(w, span, OutcomeUnauthorized, err.Error())
+			return
+		}
 	}
-	fileMapStr := vs[0]
 
-	fileMap := make(map[string][]string)
-	if err := json.Unmarshal([]byte(fileMapStr), &fileMap); err != nil {
-		writeError(w, InvalidMapFieldMessage)
-		return
+	if m.Hooks.OnRequest != nil {
+		if err := m.Hooks.OnRequest(r); err != nil {
+			m.fail(w, span, OutcomeRejected, err.Error())
+			return
+		}
+	}
+
+	// m is a copy for this request, so the schema chosen is used by all the
+	// stages of it, even if the resolver would return another meanwhile
+	if m.schemaResolver != nil {
+		s, err := m.schemaResolver(r)
+		if err == nil && s == nil {
+			err = errors.New(SchemaNotFoundMessage)
+		}
+
+		if err != nil {
+			m.fail(w, span, OutcomeSchemaNotFound, err.Error())
+			return
+		}
+		m.Schema = s
 	}
 
-	batching := true
-	ops := make([]operationField, 0)
-	if err := json.Unmarshal([]byte(opsStr), &ops); err != nil {
-		batching = false
-		op := operationField{}
-		err = json.Unmarshal([]byte(opsStr), &op)
-		if err != nil || len(op.Query) == 0 || op.Variables == nil {
-			writeError(w, InvalidOperationsFieldMessage)
+	// when the operations must be checked before the files are read, the
+	// request is parsed as soon as the "operations" and "map" are received
+	var req *request
+	var beforeFiles func(*multipart.Form) error
+	if m.Hooks.AuthorizeOperations != nil || m.earlyValidation || m.trustedDocuments != nil || m.queryStore != nil {
+		beforeFiles = func(form *multipart.Form) error {
+			var err error
+			if req, err = m.parseRequest(form); err != nil {
+				return err
+			}
+
+			if m.Hooks.AuthorizeOperations != nil {
+				if err := m.Hooks.AuthorizeOperations(r, req.operations()); err != nil {
+					return requestError{outcome: OutcomeUnauthorized, message: err.Error(), batching: req.batching}
+				}
+			}
+
+			if m.earlyValidation {
+				return m.validateRequest(req)
+			}
+			return nil
+		}
+	}
+
+	start := time.Now()
+	parseCtx, parseSpan := m.tracer.Start(ctx, "graphqlmultipart.parse")
+	form, err := m.readForm(parseCtx, r, beforeFiles)
+	if err != nil {
+		outcome := errorOutcome(err)
+		recordError(parseSpan, outcome, err)
+		parseSpan.End()
+
+		if outcome != OutcomeFailedToParseForm {
+			m.failRequest(w, span, err, outcome)
 			return
 		}
 
-		ops = append(ops, op)
+		log.Printf("[MultipartHandler] Fail do parse multipart form: %s", err.Error())
+		m.fail(w, span, outcome, FailedToParseFormMessage)
+		return
+	}
+	m.metrics.FormParsed(time.Since(start))
+
+	files := 0
+	var size int64
+	for _, fhs := range form.File {
+		for _, fh := range fhs {
+			size += fh.Size
+			files++
+		}
+	}
+	m.metrics.FilesReceived(files)
+	parseSpan.SetAttributes(attrFileCount.Int(files), attrBytes.Int64(size))
+	parseSpan.End()
+	span.SetAttributes(attrFileCount.Int(files), attrBytes.Int64(size))
+
+	if req == nil {
+		if req, err = m.parseRequest(form); err != nil {
+			m.failRequest(w, span, err, errorOutcome(err))
+			return
+		}
 	}
 
-	if len(ops) == 0 {
-		writeError(w, InvalidOperationsFieldMessage)
+	warnings, err := m.checkConformance(form, req)
+	if err != nil {
+		m.failRequest(w, span, err, OutcomeNonConformant)
 		return
 	}
 
-	results := make([]*graphql.Result, len(ops))
+	ops, batching, fileMap := req.ops, req.batching, req.fileMap
 
-	for i, op := range ops {
-		if batching {
-			op.mapPrefix = fmt.Sprintf("%d.variables.", i)
-		} else {
-			op.mapPrefix = "variables."
+	fail := m.fail
+	if batching {
+		fail = m.failBatch
+		m.metrics.BatchReceived(len(ops))
+		span.SetAttributes(attrBatchSize.Int(len(ops)))
+	}
+
+	if m.Hooks.BeginBatch != nil {
+		tx, err := m.Hooks.BeginBatch(ctx)
+		if err != nil {
+			fail(w, span, OutcomeTransactionFailed, err.Error())
+			return
 		}
-		results[i] = m.execute(op, fileMap, r)
+		ctx = context.WithValue(ctx, transactionKey{}, tx)
+	}
+
+	results, outcomes := m.executeAll(ctx, ops, batching, fileMap, r)
+
+	if m.Hooks.BeginBatch != nil {
+		m.endBatch(ctx, form, results, outcomes)
+	}
+
+	outcome := OutcomeSuccess
+	for _, o := range outcomes {
+		if o != OutcomeSuccess {
+			outcome = o
+			break
+		}
+	}
+	m.metrics.RequestHandled(outcome)
+	if outcome != OutcomeSuccess {
+		span.SetAttributes(attrErrorClass.String(outcome))
+		span.SetStatus(codes.Error, outcome)
+	}
+
+	if len(warnings) > 0 {
+		addWarnings(results, warnings)
+	}
+
+	if m.Hooks.OnResponse != nil {
+		m.Hooks.OnResponse(ctx, results)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -156,60 +327,324 @@ func (m MultipartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(buff)
 }
 
-func (m MultipartHandler) execute(op operationField, fMap map[string][]string, r *http.Request) *graphql.Result {
+// executeAll executes the operations of the request, concurrently if
+// configured, returning their results and outcomes in the same order
+func (m MultipartHandler) executeAll(ctx context.Context, ops []operationField, batching bool, fileMap map[string][]mapPath, r *http.Request) ([]*graphql.Result, []string) {
+	results := make([]*graphql.Result, len(ops))
+	outcomes := make([]string, len(ops))
+
+	run := func(i int) {
+		op := ops[i]
+		if batching {
+			op.mapPrefix = []string{strconv.Itoa(i)}
+		}
+
+		results[i], outcomes[i] = m.execute(ctx, i, op, fileMap, r, results)
+	}
+
+	// operations referencing previous results must wait for them
+	concurrent := batching && m.batchConcurrency > 1
+	for _, op := range ops {
+		if concurrent && hasReferences(*op.Variables) {
+			concurrent = false
+		}
+	}
+
+	if concurrent {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, m.batchConcurrency)
+		for i := range ops {
+			vars := copyValue(*ops[i].Variables).(map[string]interface{})
+			ops[i].Variables = &vars
+			if ops[i].Extensions != nil {
+				ops[i].Extensions = copyValue(ops[i].Extensions).(map[string]interface{})
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer func() { <-sem }()
+				defer wg.Done()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range ops {
+			run(i)
+		}
+	}
+
+	return results, outcomes
+}
+
+// endBatch commits the transaction of the request if all operations succeeded,
+// otherwise it is rolled back and the files of the request are deleted
+func (m MultipartHandler) endBatch(ctx context.Context, form *multipart.Form, results []*graphql.Result, outcomes []string) {
+	tx, _ := TransactionFromContext(ctx)
+
+	failed := false
+	for i, res := range results {
+		if res.HasErrors() || outcomes[i] != OutcomeSuccess {
+			failed = true
+		}
+	}
+
+	if !failed {
+		if m.Hooks.CommitBatch == nil {
+			return
+		}
+
+		err := m.Hooks.CommitBatch(ctx, tx)
+		if err == nil {
+			return
+		}
+
+		for i, res := range results {
+			res.Errors = append(res.Errors, gqlerrors.FormatError(err))
+			outcomes[i] = OutcomeTransactionFailed
+		}
+		form.RemoveAll()
+		return
+	}
+
+	if m.Hooks.RollbackBatch != nil {
+		if err := m.Hooks.RollbackBatch(ctx, tx); err != nil {
+			log.Printf("[MultipartHandler] Fail to rollback batch: %s", err.Error())
+		}
+	}
+
+	for _, res := range results {
+		if !res.HasErrors() {
+			res.Errors = append(res.Errors, gqlerrors.NewFormattedError(BatchRolledBackMessage))
+		}
+	}
+	form.RemoveAll()
+}
+
+// fail writes the message as the response, recording the outcome of the
+// request in the metrics and span
+func (m MultipartHandler) fail(w http.ResponseWriter, span trace.Span, outcome, message string) {
+	m.metrics.RequestHandled(outcome)
+	recordError(span, outcome, errors.New(message))
+	writeError(w, message)
+}
+
+// failRequest writes the error of the request in the shape expected by the
+// client
+func (m MultipartHandler) failRequest(w http.ResponseWriter, span trace.Span, err error, outcome string) {
+	var reqErr requestError
+	if errors.As(err, &reqErr) && len(reqErr.errors) > 0 {
+		m.metrics.RequestHandled(outcome)
+		recordError(span, outcome, err)
+		writeResult(w, graphql.Result{Errors: reqErr.errors}, reqErr.batching)
+		return
+	}
+
+	if errors.As(err, &reqErr) && reqErr.batching {
+		m.failBatch(w, span, outcome, err.Error())
+		return
+	}
+
+	m.fail(w, span, outcome, err.Error())
+}
+
+// failBatch is the same as fail, but writes the message as a batch response
+func (m MultipartHandler) failBatch(w http.ResponseWriter, span trace.Span, outcome, message string) {
+	m.metrics.RequestHandled(outcome)
+	recordError(span, outcome, errors.New(message))
+	writeBatchError(w, message)
+}
+
+func (m MultipartHandler) execute(ctx context.Context, i int, op operationField, fMap map[string][]mapPath, r *http.Request, results []*graphql.Result) (*graphql.Result, string) {
+	_, span := m.tracer.Start(ctx, "graphqlmultipart.inject")
+	span.SetAttributes(attrOperationName.String(op.OperationName))
+
+	vars, err := resolveReferences(*op.Variables, i, results)
+	if err != nil {
+		recordError(span, OutcomeInvalidReference, err)
+		span.End()
+		return &graphql.Result{
+			Errors: gqlerrors.FormatErrors(err),
+		}, OutcomeInvalidReference
+	}
+	*op.Variables = vars.(map[string]interface{})
 
 	errs := make([]error, 0)
+	outcome := OutcomeSuccess
+
+	// a query that can't be parsed is left to the execution to report
+	doc, def := op.doc, op.def
+	var docErrs []gqlerrors.FormattedError
+	switch {
+	case doc != nil:
+	case m.documents != nil:
+		doc, docErrs = m.document(op.Query)
+	default:
+		doc = parseQuery(op.Query)
+	}
 
+	if doc != nil && def == nil {
+		def = findOperation(doc, op.OperationName)
+	}
+
+	files := 0
 	for f, ps := range fMap {
+		// only the paths of this operation are considered, so a file missing
+		// or mapped wrongly only fails the operations using it
+		ops := make([]mapPath, 0, len(ps))
+		for _, p := range ps {
+			if path, ok := p.relative(op.mapPrefix...); ok && mapRoots[path[0]] {
+				ops = append(ops, p)
+			}
+		}
+
+		if len(ops) == 0 {
+			continue
+		}
 
 		if _, ok := r.MultipartForm.File[f]; !ok {
 			errs = append(errs, fmt.Errorf(fmt.Sprintf(MissingFileMessage, f)))
+			if outcome == OutcomeSuccess {
+				outcome = OutcomeMissingFile
+			}
 			continue
 		}
 
-		for _, p := range ps {
-			if !strings.HasPrefix(p, op.mapPrefix) {
-				continue
+		for _, p := range ops {
+			path, _ := p.relative(op.mapPrefix...)
+			root, path := path[0], path[1:]
+
+			if root == "variables" && def != nil && len(path) > 0 {
+				o, err := m.checkUploadPath(def, f, p, path)
+				if err != nil {
+					errs = append(errs, err)
+					if outcome == OutcomeSuccess {
+						outcome = o
+					}
+					continue
+				}
 			}
 
-			vars, ok := injectFile(
-				r.MultipartForm.File[f][0],
-				*op.Variables,
-				p[len(op.mapPrefix):],
-			)
+			var v interface{}
+			ok := false
+			if len(path) > 0 && root == "variables" {
+				if v, ok = injectFile(r.MultipartForm.File[f][0], *op.Variables, path); ok {
+					*op.Variables = v.(map[string]interface{})
+				}
+			} else if len(path) > 0 && root == "extensions" {
+				if v, ok = injectFile(r.MultipartForm.File[f][0], op.Extensions, path); ok {
+					op.Extensions = v.(map[string]interface{})
+				}
+			}
 
 			if !ok {
 				errs = append(errs, fmt.Errorf(InvalidMapPathMessage, p, f))
+				if outcome == OutcomeSuccess {
+					outcome = OutcomeInvalidMapPath
+				}
 				continue
 			}
-			*op.Variables = vars.(map[string]interface{})
+			files++
 		}
 	}
 
+	if m.strictUploads && def != nil && len(errs) == 0 {
+		if err := m.checkInlineUploads(def, *op.Variables); err != nil {
+			errs = append(errs, err)
+			outcome = OutcomeInlineUpload
+		}
+	}
+
+	if files > 0 && m.uploadsOnlyForMutations {
+		if def != nil && def.Operation != ast.OperationTypeMutation {
+			errs = append(errs, fmt.Errorf(OperationTypeNotAllowedMessage, i, def.Operation))
+			if outcome == OutcomeSuccess {
+				outcome = OutcomeOperationTypeNotAllowed
+			}
+		}
+	}
+
+	if def != nil && len(errs) == 0 {
+		if o, err := m.checkLimits(i, doc, def, *op.Variables); err != nil {
+			errs = append(errs, err)
+			outcome = o
+		}
+	}
+
+	span.SetAttributes(attrFileCount.Int(files))
 	if len(errs) > 0 {
+		recordError(span, outcome, errs[0])
+		span.End()
 		return &graphql.Result{
 			Errors: gqlerrors.FormatErrors(errs...),
+		}, outcome
+	}
+	span.End()
+
+	if m.Hooks.OnOperation != nil {
+		var t string
+		if def != nil {
+			t = def.Operation
+		}
+
+		err := m.Hooks.OnOperation(ctx, Operation{
+			Index:         i,
+			Type:          t,
+			Query:         op.Query,
+			OperationName: op.OperationName,
+			Variables:     *op.Variables,
+			Extensions:    op.Extensions,
+		})
+		if err != nil {
+			return &graphql.Result{
+				Errors: gqlerrors.FormatErrors(err),
+			}, OutcomeRejected
 		}
 	}
 
-	return graphql.Do(graphql.Params{
-		Schema:         *m.Schema,
-		RequestString:  op.Query,
-		VariableValues: *op.Variables,
-		OperationName:  op.OperationName,
-		Context:        r.Context(),
-	})
-}
+	if op.Extensions != nil {
+		ctx = context.WithValue(ctx, extensionsKey{}, op.Extensions)
+	}
 
-func injectFile(f *multipart.FileHeader, vars interface{}, path string) (interface{}, bool) {
-	var field, next string
+	ctx, span = m.tracer.Start(ctx, "graphqlmultipart.execute")
+	defer span.End()
+	span.SetAttributes(attrOperationName.String(op.OperationName))
+
+	start := time.Now()
+	var res *graphql.Result
+	switch {
+	case len(docErrs) > 0:
+		res = &graphql.Result{Errors: docErrs}
+	case op.doc != nil || m.documents != nil:
+		res = graphql.Execute(graphql.ExecuteParams{
+			Schema:        *m.Schema,
+			AST:           doc,
+			Args:          *op.Variables,
+			OperationName: op.OperationName,
+			Context:       ctx,
+		})
+	default:
+		res = graphql.Do(graphql.Params{
+			Schema:         *m.Schema,
+			RequestString:  op.Query,
+			VariableValues: *op.Variables,
+			OperationName:  op.OperationName,
+			Context:        ctx,
+		})
+	}
+	m.metrics.OperationExecuted(time.Since(start))
 
-	field = path
-	if i := strings.Index(path, "."); i != -1 {
-		field = path[0:i]
-		next = path[i+1:]
+	if res.HasErrors() {
+		span.SetStatus(codes.Error, res.Errors[0].Message)
 	}
 
+	return res, outcome
+}
+
+func injectFile(f *multipart.FileHeader, vars interface{}, path []string) (interface{}, bool) {
+	field, next := path[0], path[1:]
+
 	switch v := vars.(type) {
 	case map[string]interface{}:
 		_, ok := v[field]
@@ -229,8 +664,8 @@ func injectFile(f *multipart.FileHeader, vars interface{}, path string) (interfa
 		v[field] = t
 		return v, true
 	case []interface{}:
-		index, err := strconv.Atoi(field)
-		if err != nil {
+		index, ok := index(field)
+		if !ok {
 			return v, false
 		}
 
@@ -255,13 +690,56 @@ func injectFile(f *multipart.FileHeader, vars interface{}, path string) (interfa
 	}
 }
 
-func writeError(w http.ResponseWriter, errs ...string) {
+// copyValue deep copies the maps and slices of a decoded JSON value, so
+// concurrent operations don't share any of their variables
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = copyValue(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = copyValue(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+func errorResult(errs ...string) graphql.Result {
 	fErrs := make([]gqlerrors.FormattedError, len(errs))
 	for i, err := range errs {
 		fErrs[i] = gqlerrors.NewFormattedError(err)
 	}
 
+	return graphql.Result{Errors: fErrs}
+}
+
+func writeError(w http.ResponseWriter, errs ...string) {
 	w.WriteHeader(http.StatusOK)
-	buff, _ := json.Marshal(graphql.Result{Errors: fErrs})
+	buff, _ := json.Marshal(errorResult(errs...))
+	w.Write(buff)
+}
+
+func writeBatchError(w http.ResponseWriter, errs ...string) {
+	w.WriteHeader(http.StatusOK)
+	buff, _ := json.Marshal([]graphql.Result{errorResult(errs...)})
+	w.Write(buff)
+}
+
+func writeResult(w http.ResponseWriter, res graphql.Result, batching bool) {
+	w.WriteHeader(http.StatusOK)
+	if batching {
+		buff, _ := json.Marshal([]graphql.Result{res})
+		w.Write(buff)
+		return
+	}
+
+	buff, _ := json.Marshal(res)
 	w.Write(buff)
 }
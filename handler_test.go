@@ -1,13 +1,19 @@
 package graphqlmultipart_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
 	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
@@ -101,7 +107,37 @@ func TestHandlerShouldValidateRequest(t *testing.T) {
 				},
 				make(map[string]string),
 			),
-			respo: getJSONError(graphqlmultipart.InvalidOperationsFieldMessage),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidOperationsFieldMessage) + "]",
+		},
+		"invalid_operaction_field_batching": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[{"query":1}]`,
+					"map":        "{}",
+				},
+				make(map[string]string),
+			),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidOperationsFieldMessage) + "]",
+		},
+		"batched_operation_without_variables": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[{"query":"query ($file: Upload){ upload(file:$file){filename} }"}]`,
+					"map":        `{"file":["0.variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidOperationsFieldMessage) + "]",
+		},
+		"batched_operation_without_query": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[{"query":"query hero{id}","variables":{}},{"variables":{}}]`,
+					"map":        "{}",
+				},
+				make(map[string]string),
+			),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidOperationsFieldMessage) + "]",
 		},
 		"invalid_map_field": test{
 			req: newFileUploadRequest(
@@ -117,12 +153,29 @@ func TestHandlerShouldValidateRequest(t *testing.T) {
 			req: newFileUploadRequest(
 				map[string]string{
 					"operations": "[{\"query\":\"query hero{id}\",\"variables\":{}}]",
-					"map":        "{\"file\":[]}",
+					"map":        "{\"file\":[\"0.variables.file\"]}",
 				},
 				make(map[string]string),
 			),
 			respo: "[" + getJSONError(graphqlmultipart.MissingFileMessage, "file") + "]",
 		},
+		"missing_map_field_batching": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": "[]"},
+				make(map[string]string),
+			),
+			respo: "[" + getJSONError(graphqlmultipart.MapFieldMissingMessage) + "]",
+		},
+		"invalid_map_field_batching": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[{"query":"hero{id}","variables":{}}]`,
+					"map":        "[]",
+				},
+				make(map[string]string),
+			),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidMapFieldMessage) + "]",
+		},
 		"invalid_map_path": test{
 			req: newFileUploadRequest(
 				map[string]string{
@@ -214,6 +267,33 @@ func TestHandler_SchemaCanAccessUploads(t *testing.T) {
 				}
 			]`,
 		},
+		"batching_isolates_errors": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{
+							"query":"query ($file: Upload){ upload(file:$file){filename} }",
+							"variables":{"file":null}
+						},
+						{
+							"query":"query ($file: Upload){ upload(file:$file){filename} }",
+							"variables":{"file":null}
+						},
+						{
+							"query":"query ($file: Upload){ upload(file:$file){filename} }",
+							"variables":{"file":null}
+						}
+					]`,
+					"map": `{"file":["0.variables.file"],"missing":["1.variables.file"],"other":["2.variables.other"]}`,
+				},
+				map[string]string{"file": "handler.go", "other": "handler.go"},
+			),
+			respo: `[
+				{"data":{"upload":{"filename":"handler.go"}}},
+				` + getJSONError(graphqlmultipart.MissingFileMessage, "missing") + `,
+				` + getJSONError(graphqlmultipart.InvalidMapPathMessage, "2.variables.other", "other") + `
+			]`,
+		},
 		"simple_deeper": test{
 			req: newFileUploadRequest(
 				map[string]string{
@@ -278,3 +358,122 @@ func TestHandler_SchemaCanAccessUploads(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_BatchConcurrency(t *testing.T) {
+	const size = 8
+
+	ops := make([]string, size)
+	paths := make([]string, size)
+	expected := make([]string, size)
+	for i := 0; i < size; i++ {
+		ops[i] = fmt.Sprintf(
+			`{"query":"query ($file: Upload){ op%d: upload(file:$file){filename} }","variables":{"file":null}}`,
+			i,
+		)
+		paths[i] = fmt.Sprintf(`"%d.variables.file"`, i)
+		expected[i] = fmt.Sprintf(`{"data":{"op%d":{"filename":"handler.go"}}}`, i)
+	}
+
+	var inFlight, maxInFlight int32
+	mh := graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithBatchConcurrency(3),
+		graphqlmultipart.WithHooks(graphqlmultipart.Hooks{
+			OnOperation: func(ctx context.Context, op graphqlmultipart.Operation) error {
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					m := atomic.LoadInt32(&maxInFlight)
+					if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+		}),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": "[" + strings.Join(ops, ",") + "]",
+			"map":        `{"file":[` + strings.Join(paths, ",") + `]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.JSONEq(t, "["+strings.Join(expected, ",")+"]", string(body))
+	require.True(t, maxInFlight > 1, "operations should have run concurrently")
+	require.True(t, maxInFlight <= 3, "no more than 3 operations should run at once")
+}
+
+func TestHandler_LimitsParts(t *testing.T) {
+	newRequest := func(files int, headers int) *http.Request {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		w.WriteField("operations", `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}`)
+		w.WriteField("map", `{"0":["variables.file"]}`)
+		for i := 0; i < files; i++ {
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%d"; filename="%d.txt"`, i, i))
+			for j := 0; j < headers; j++ {
+				h.Set(fmt.Sprintf("X-Header-%d", j), "value")
+			}
+			fw, _ := w.CreatePart(h)
+			fw.Write([]byte("content"))
+		}
+		w.Close()
+
+		req, _ := http.NewRequest("POST", "/graphql", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		return req
+	}
+
+	cases := map[string]struct {
+		req   *http.Request
+		respo string
+	}{
+		"inside_limits": {
+			req:   newRequest(2, 10),
+			respo: `{"data":{"upload":{"filename":"0.txt"}}}`,
+		},
+		"too_many_parts": {
+			req:   newRequest(3000, 0),
+			respo: getJSONError(graphqlmultipart.FailedToParseFormMessage),
+		},
+		"too_many_headers": {
+			req:   newRequest(100, 200),
+			respo: getJSONError(graphqlmultipart.FailedToParseFormMessage),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler().ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestHandler_ZeroValue(t *testing.T) {
+	mh := graphqlmultipart.MultipartHandler{Schema: &testutil.Schema}
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}`,
+			"map":        `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go"}}}`, string(body))
+}
@@ -0,0 +1,116 @@
+package graphqlmultipart
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/textproto"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Operation is a GraphQL operation received in the "operations" field, with
+// its files already injected into the Variables
+type Operation struct {
+	// Index is the position of the operation in a batched request, or 0
+	Index int
+	// Type is "query", "mutation" or "subscription", it's empty when the
+	// query is not valid
+	Type          string
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+	// Extensions are the "extensions" of the operation, with the files mapped
+	// to them already injected
+	Extensions map[string]interface{}
+}
+
+// Hooks are callbacks called at each stage of the handling of a multipart
+// request, any of them can be nil
+type Hooks struct {
+	// Authorize is the authentication variant of OnRequest, it is called at
+	// the same point, just before it, but its errors are reported with the
+	// "unauthorized" outcome instead of "rejected", so the metrics and traces
+	// can tell unauthenticated clients apart. Returning a error rejects the
+	// request without reading its body
+	Authorize func(r *http.Request) error
+
+	// AuthorizeOperations is called after the "operations" and "map" fields
+	// are read, but before any file, returning a error rejects the request
+	// without reading them. The Variables of the operations don't have the
+	// files and the files must be sent after those fields, as the spec says
+	AuthorizeOperations func(r *http.Request, ops []Operation) error
+
+	// OnRequest is called before the body is parsed, after Authorize,
+	// returning a error rejects the request with the "rejected" outcome
+	OnRequest func(r *http.Request) error
+
+	// OnFile is called for every file part before it is read, returning a error
+	// rejects the request, the returned io.Reader will be used as the content
+	// of the file, so it can wrap the body received. Errors returned while
+	// reading it fail the request as FailedToParseFormMessage
+	OnFile func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error)
+
+	// OnOperation is called before a operation is executed, returning a error
+	// prevents the operation from being executed and reports it as its result
+	OnOperation func(ctx context.Context, op Operation) error
+
+	// OnResponse is called with the results before they are written
+	OnResponse func(ctx context.Context, results []*graphql.Result)
+
+	// BeginBatch is called before the operations of a request are executed (a
+	// single operation is handled as a batch of one), the transaction returned
+	// is available to them through TransactionFromContext. Returning a error
+	// rejects the request
+	BeginBatch func(ctx context.Context) (interface{}, error)
+
+	// CommitBatch is called after all operations were executed without errors,
+	// if it fails the request is handled as failed
+	CommitBatch func(ctx context.Context, tx interface{}) error
+
+	// RollbackBatch is called when any of the operations failed, the files
+	// stored for the request are deleted after it
+	RollbackBatch func(ctx context.Context, tx interface{}) error
+}
+
+type transactionKey struct{}
+
+// TransactionFromContext retrieves the transaction started by the
+// Hooks.BeginBatch for the request
+func TransactionFromContext(ctx context.Context) (interface{}, bool) {
+	tx := ctx.Value(transactionKey{})
+	return tx, tx != nil
+}
+
+type extensionsKey struct{}
+
+// ExtensionsFromContext retrieves the "extensions" of the operation being
+// executed, with the files mapped to them
+func ExtensionsFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	ext, ok := ctx.Value(extensionsKey{}).(map[string]interface{})
+	return ext, ok
+}
+
+// WithHooks sets the Hooks of the handler
+func WithHooks(h Hooks) Option {
+	return func(m *MultipartHandler) {
+		m.Hooks = h
+	}
+}
+
+// BatchRolledBackMessage is shown in the results of the operations that were
+// executed, but had their changes rolled back because other one failed
+var BatchRolledBackMessage = "Operation was rolled back because other operation of the request failed"
+
+// rejectedError is a error returned by a hook rejecting the request
+type rejectedError struct {
+	err error
+}
+
+func (e rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e rejectedError) Unwrap() error {
+	return e.err
+}
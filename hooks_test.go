@@ -0,0 +1,330 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newHookedHandler(h graphqlmultipart.Hooks) http.Handler {
+	return graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithHooks(h),
+	)
+}
+
+func newSimpleUploadRequest() *http.Request {
+	return newFileUploadRequest(
+		map[string]string{
+			"operations": `{
+				"query":"query($file:Upload) { upload(file: $file){ filename, size } }",
+				"variables":{"file":null}
+			}`,
+			"map": `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	)
+}
+
+func TestHooks_OnRequestCanReject(t *testing.T) {
+	mh := newHookedHandler(graphqlmultipart.Hooks{
+		OnRequest: func(r *http.Request) error {
+			return errors.New("not today")
+		},
+		OnFile: func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+			t.Fatal("files should not be read")
+			return body, nil
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newSimpleUploadRequest())
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, getJSONError("not today"), string(body))
+}
+
+func TestHooks_OnFileCanRejectOrWrap(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			OnFile: func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+				return nil, errors.New("file " + field + " is not allowed")
+			},
+		})
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, getJSONError("file file is not allowed"), string(body))
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			OnFile: func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+				require.Contains(t, header.Get("Content-Disposition"), `filename="handler.go"`)
+				return io.LimitReader(body, 10), nil
+			},
+		})
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go","size":10}}}`, string(body))
+	})
+}
+
+func TestHooks_OnOperationAndOnResponse(t *testing.T) {
+	var ops []graphqlmultipart.Operation
+	mh := newHookedHandler(graphqlmultipart.Hooks{
+		OnOperation: func(ctx context.Context, op graphqlmultipart.Operation) error {
+			ops = append(ops, op)
+			if op.Index == 1 {
+				return errors.New("quota exceeded")
+			}
+			return nil
+		},
+		OnResponse: func(ctx context.Context, results []*graphql.Result) {
+			require.Len(t, results, 2)
+			results[0].Extensions = map[string]interface{}{"audited": true}
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `[
+				{"query":"query A($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"A"},
+				{"query":"query B($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"B"}
+			]`,
+			"map": `{"file":["0.variables.file","1.variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.Len(t, ops, 2)
+	require.Equal(t, "A", ops[0].OperationName)
+	require.Equal(t, "B", ops[1].OperationName)
+	require.IsType(t, &multipart.FileHeader{}, ops[0].Variables["file"])
+
+	require.JSONEq(t, `[
+		{"data":{"upload":{"filename":"handler.go"}},"extensions":{"audited":true}},
+		`+getJSONError("quota exceeded")+`
+	]`, string(body))
+}
+
+func TestHooks_BatchTransaction(t *testing.T) {
+	type tx struct {
+		committed  bool
+		rolledBack bool
+	}
+
+	newTxHandler := func(current **tx, files *[]*multipart.FileHeader) http.Handler {
+		return newHookedHandler(graphqlmultipart.Hooks{
+			BeginBatch: func(ctx context.Context) (interface{}, error) {
+				*current = &tx{}
+				return *current, nil
+			},
+			OnOperation: func(ctx context.Context, op graphqlmultipart.Operation) error {
+				v, ok := graphqlmultipart.TransactionFromContext(ctx)
+				require.True(t, ok)
+				require.Equal(t, *current, v)
+				if fh, ok := op.Variables["file"].(*multipart.FileHeader); ok {
+					*files = append(*files, fh)
+				}
+				return nil
+			},
+			CommitBatch: func(ctx context.Context, v interface{}) error {
+				v.(*tx).committed = true
+				return nil
+			},
+			RollbackBatch: func(ctx context.Context, v interface{}) error {
+				v.(*tx).rolledBack = true
+				return nil
+			},
+		})
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		var current *tx
+		var files []*multipart.FileHeader
+		mh := newTxHandler(&current, &files)
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newFileUploadRequest(
+			map[string]string{
+				"operations": `[
+					{"query":"mutation ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}},
+					{"query":"mutation ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}
+				]`,
+				"map": `{"file":["0.variables.file","1.variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `[
+			{"data":{"upload":{"filename":"handler.go"}}},
+			{"data":{"upload":{"filename":"handler.go"}}}
+		]`, string(body))
+		require.True(t, current.committed)
+		require.False(t, current.rolledBack)
+
+		require.Len(t, files, 2)
+		f, err := files[0].Open()
+		require.NoError(t, err)
+		f.Close()
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		var current *tx
+		var files []*multipart.FileHeader
+		mh := newTxHandler(&current, &files)
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newFileUploadRequest(
+			map[string]string{
+				"operations": `[
+					{"query":"mutation ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}},
+					{"query":"mutation ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}
+				]`,
+				"map": `{"file":["0.variables.file"],"missing":["1.variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, `[
+			{
+				"data":{"upload":{"filename":"handler.go"}},
+				"errors":[{"message":`+strconv.Quote(graphqlmultipart.BatchRolledBackMessage)+`,"locations":[]}]
+			},
+			`+getJSONError(graphqlmultipart.MissingFileMessage, "missing")+`
+		]`, string(body))
+		require.False(t, current.committed)
+		require.True(t, current.rolledBack)
+
+		require.Len(t, files, 1)
+		_, err := files[0].Open()
+		require.Error(t, err, "files should have been deleted")
+	})
+}
+
+func TestHooks_Authorization(t *testing.T) {
+	noFiles := func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+		t.Fatal("files should not be read")
+		return body, nil
+	}
+
+	t.Run("authorize", func(t *testing.T) {
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			Authorize: func(r *http.Request) error {
+				if r.Header.Get("Authorization") == "" {
+					return errors.New("unauthenticated")
+				}
+				return nil
+			},
+			OnRequest: func(r *http.Request) error {
+				t.Fatal("OnRequest should not be called for unauthenticated requests")
+				return nil
+			},
+			OnFile: noFiles,
+		})
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, getJSONError("unauthenticated"), string(body))
+	})
+
+	t.Run("authorize_operations", func(t *testing.T) {
+		var received []graphqlmultipart.Operation
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			AuthorizeOperations: func(r *http.Request, ops []graphqlmultipart.Operation) error {
+				received = ops
+				for _, op := range ops {
+					if op.Type != "mutation" {
+						return errors.New(op.OperationName + " is not allowed")
+					}
+				}
+				return nil
+			},
+			OnFile: noFiles,
+		})
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newFileUploadRequest(
+			map[string]string{
+				"operations": `[
+					{"query":"mutation A($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"A"},
+					{"query":"query B($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"B"}
+				]`,
+				"map": `{"file":["0.variables.file","1.variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+
+		require.JSONEq(t, "["+getJSONError("B is not allowed")+"]", string(body))
+		require.Len(t, received, 2)
+		require.Equal(t, "mutation", received[0].Type)
+		require.Equal(t, "query", received[1].Type)
+		require.Nil(t, received[0].Variables["file"])
+	})
+
+	t.Run("authorize_operations_allows", func(t *testing.T) {
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			AuthorizeOperations: func(r *http.Request, ops []graphqlmultipart.Operation) error {
+				return nil
+			},
+		})
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newSimpleUploadRequest())
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.Contains(t, string(body), `"filename":"handler.go"`)
+	})
+
+	t.Run("files_before_operations", func(t *testing.T) {
+		mh := newHookedHandler(graphqlmultipart.Hooks{
+			AuthorizeOperations: func(r *http.Request, ops []graphqlmultipart.Operation) error {
+				t.Fatal("operations were not sent yet")
+				return nil
+			},
+			OnFile: noFiles,
+		})
+
+		buf := new(bytes.Buffer)
+		mw := multipart.NewWriter(buf)
+		p, _ := mw.CreateFormFile("file", "file.txt")
+		p.Write([]byte("content"))
+		mw.WriteField("operations", `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}`)
+		mw.WriteField("map", `{"file":["variables.file"]}`)
+		mw.Close()
+
+		r, _ := http.NewRequest("POST", "/graphql", buf)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, r)
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, getJSONError(graphqlmultipart.FilesBeforeOperationsMessage), string(body))
+	})
+}
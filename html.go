@@ -0,0 +1,250 @@
+package graphqlmultipart
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	// HTMLFormFieldMessage is shown when a form posted to a handler with
+	// WithHTMLForm has a field reserved to the spec
+	HTMLFormFieldMessage = "Field \"%[1]s\" is not accepted, the operation of this form is defined by the server"
+
+	// CrossOriginFormMessage is shown when a form is posted from a origin that
+	// is not allowed by the HTMLForm
+	CrossOriginFormMessage = "This form has been blocked as a possible Cross-Site Request Forgery (CSRF), it was sent from a origin that is not allowed: %[1]s"
+)
+
+// HTMLForm is the operation executed for the plain HTML forms posted to the
+// handler, as the forms can't send the "operations" and "map" fields
+type HTMLForm struct {
+	// Query is the document executed for the forms
+	Query string
+
+	// DocumentID is used when the Query is empty, it is the id of a trusted
+	// document when WithTrustedDocuments is used, or the sha256 hash of a
+	// query of the WithPersistedQueries store otherwise
+	DocumentID string
+
+	// OperationName chooses the operation of the document to execute
+	OperationName string
+
+	// AllowedOrigins are the origins the forms can be posted from, like
+	// "https://example.com", when empty only the host of the handler is
+	// allowed
+	AllowedOrigins []string
+}
+
+// WithHTMLForm makes the handler execute the operation for plain HTML forms,
+// like a <form enctype="multipart/form-data"> with no JavaScript. As the
+// operation is the same for every form, a handler should be created for each
+// route, and it rejects the requests with the "operations" or "map" fields.
+//
+// The variables are filled with the fields named like them, the values are
+// converted to the types of the variables, with unchecked checkboxes as false
+// for Boolean variables. The fields of input objects are named with dots like
+// "input.name", lists receive all the values of a field and file inputs are
+// mapped to the Upload variables with their names. The fields can be sent in
+// any order, so hooks and options that need the operations before the files
+// will only run after them.
+//
+// Browsers post forms cross-site without a preflight and can't add headers to
+// them, so instead of the headers of WithCSRFPrevention, the Origin (or the
+// Referer, when there is no Origin) of the requests must be one of the
+// AllowedOrigins. Otherwise any site could run the operation with the
+// cookies of its users
+func WithHTMLForm(f HTMLForm) Option {
+	return func(m *MultipartHandler) {
+		m.htmlForm = &f
+	}
+}
+
+// checkOrigin returns a error if the request was not sent from a origin
+// allowed, the requests without a origin are not allowed
+func (f HTMLForm) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		origin = ""
+		if u, err := url.Parse(r.Referer()); err == nil && u.Host != "" {
+			origin = u.Scheme + "://" + u.Host
+		}
+	}
+
+	if origin == "" {
+		return fmt.Errorf(CrossOriginFormMessage, "(none)")
+	}
+
+	if len(f.AllowedOrigins) == 0 {
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return nil
+		}
+	}
+
+	for _, o := range f.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(CrossOriginFormMessage, origin)
+}
+
+// normalizeHTMLForm fills the "operations" and "map" fields of the form with
+// the operation of the HTMLForm and the values of the other fields
+func (m MultipartHandler) normalizeHTMLForm(form *multipart.Form) {
+	f := m.htmlForm
+	op := map[string]interface{}{}
+	if f.OperationName != "" {
+		op["operationName"] = f.OperationName
+	}
+
+	query := f.Query
+	switch {
+	case query != "":
+		op["query"] = query
+	case m.trustedDocuments != nil:
+		op["documentId"] = f.DocumentID
+		query, _ = m.trustedDocuments.Lookup(f.DocumentID)
+	default:
+		// the lookup is left to parseRequest, so unknown ids fail as
+		// any other persisted query
+		op["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": f.DocumentID},
+		}
+		if m.queryStore != nil {
+			query, _ = m.queryStore.Get(f.DocumentID)
+		}
+	}
+
+	vars := make(map[string]interface{})
+	fileMap := make(map[string][]string)
+	if def := parseOperation(query, f.OperationName); def != nil {
+		for _, vd := range def.VariableDefinitions {
+			name := vd.Variable.Name.Value
+			if v, ok := formValue(form, typeFromAST(*m.Schema, vd.Type), name, fileMap); ok {
+				vars[name] = v
+			}
+		}
+	}
+	op["variables"] = vars
+
+	b, _ := json.Marshal(op)
+	form.Value["operations"] = []string{string(b)}
+
+	b, _ = json.Marshal(fileMap)
+	form.Value["map"] = []string{string(b)}
+}
+
+// formValue reads the value of the variable or input field from the form,
+// converting it to its type, and maps the files of Upload values into the
+// fileMap. It returns false if the form has no value for it
+func formValue(form *multipart.Form, t graphql.Type, name string, fileMap map[string][]string) (interface{}, bool) {
+	if nn, ok := t.(*graphql.NonNull); ok {
+		t = nn.OfType
+	}
+
+	switch t := t.(type) {
+	case *graphql.InputObject:
+		fs := make(map[string]interface{})
+		for n, f := range t.Fields() {
+			if v, ok := formValue(form, f.Type, name+"."+n, fileMap); ok {
+				fs[n] = v
+			}
+		}
+		return fs, len(fs) > 0
+	case *graphql.List:
+		inner := t.OfType
+		if nn, ok := inner.(*graphql.NonNull); ok {
+			inner = nn.OfType
+		}
+
+		if inner == Upload {
+			fhs := form.File[name]
+			vs := make([]interface{}, len(fhs))
+			for i := range fhs {
+				// each file of a multiple file input gets its own field, as
+				// only one file can be mapped by field
+				f := name
+				if i > 0 {
+					f = name + "." + strconv.Itoa(i)
+					form.File[f] = fhs[i : i+1]
+				}
+				fileMap[f] = []string{"variables." + name + "." + strconv.Itoa(i)}
+			}
+			if len(fhs) > 1 {
+				form.File[name] = fhs[:1]
+			}
+			return vs, len(fhs) > 0
+		}
+
+		ss, ok := form.Value[name]
+		if !ok {
+			return nil, false
+		}
+
+		vs := make([]interface{}, 0, len(ss))
+		for _, s := range ss {
+			if v, ok := scalarValue(inner, s); ok {
+				vs = append(vs, v)
+			}
+		}
+		return vs, true
+	case graphql.Type:
+		if t == Upload {
+			if len(form.File[name]) == 0 {
+				return nil, false
+			}
+			fileMap[name] = []string{"variables." + name}
+			return nil, true
+		}
+
+		ss, ok := form.Value[name]
+		if !ok {
+			// unchecked checkboxes are not sent
+			return false, t == graphql.Boolean
+		}
+		return scalarValue(t, ss[0])
+	}
+
+	return nil, false
+}
+
+// scalarValue converts a field value to the type, values that can't be
+// converted are kept as strings for the execution to report them. Empty
+// values of types other than String are ignored, as they are empty inputs
+func scalarValue(t graphql.Type, s string) (interface{}, bool) {
+	if t == graphql.String {
+		return s, true
+	}
+
+	if strings.TrimSpace(s) == "" {
+		return nil, false
+	}
+
+	switch t {
+	case graphql.Int:
+		if n, err := strconv.Atoi(s); err == nil {
+			return n, true
+		}
+	case graphql.Float:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n, true
+		}
+	case graphql.Boolean:
+		if s == "on" {
+			return true, true
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, true
+		}
+	}
+
+	return s, true
+}
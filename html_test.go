@@ -0,0 +1,343 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+// formPart is a field of a HTML form, files are sent when file is informed
+type formPart struct {
+	name, value, file string
+}
+
+// newHTMLFormRequest builds the request a browser would send for a form of
+// the same origin, with the parts in the order they are informed
+func newHTMLFormRequest(t *testing.T, parts ...formPart) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for _, p := range parts {
+		if p.file == "" {
+			require.NoError(t, w.WriteField(p.name, p.value))
+			continue
+		}
+
+		content, err := ioutil.ReadFile(p.file)
+		require.NoError(t, err)
+
+		fw, err := w.CreateFormFile(p.name, p.file)
+		require.NoError(t, err)
+		fw.Write(content)
+	}
+	require.NoError(t, w.Close())
+
+	req, _ := http.NewRequest("POST", "http://example.com/graphql", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Origin", "http://example.com")
+	return req
+}
+
+// newFormSchema creates a schema with a "post" field that returns its
+// arguments as JSON, with the names of the files received
+func newFormSchema(t *testing.T) *graphql.Schema {
+	kind := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Kind",
+		Values: graphql.EnumValueConfigMap{
+			"ARTICLE": &graphql.EnumValueConfig{Value: "article"},
+			"NOTE":    &graphql.EnumValueConfig{Value: "note"},
+		},
+	})
+
+	meta := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PostMeta",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"author":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"attachment": &graphql.InputObjectFieldConfig{Type: graphqlmultipart.Upload},
+		},
+	})
+
+	var names func(v interface{}) interface{}
+	names = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case *multipart.FileHeader:
+			return v.Filename
+		case []interface{}:
+			vs := make([]interface{}, len(v))
+			for i, e := range v {
+				vs[i] = names(e)
+			}
+			return vs
+		case map[string]interface{}:
+			vs := make(map[string]interface{}, len(v))
+			for k, e := range v {
+				vs[k] = names(e)
+			}
+			return vs
+		}
+		return v
+	}
+
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"post": &graphql.Field{
+					Type: graphql.String,
+					Args: graphql.FieldConfigArgument{
+						"title":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+						"rating": &graphql.ArgumentConfig{Type: graphql.Int},
+						"score":  &graphql.ArgumentConfig{Type: graphql.Float},
+						"public": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Boolean)},
+						"tags":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+						"kind":   &graphql.ArgumentConfig{Type: kind},
+						"meta":   &graphql.ArgumentConfig{Type: meta},
+						"cover":  &graphql.ArgumentConfig{Type: graphqlmultipart.Upload},
+						"photos": &graphql.ArgumentConfig{Type: graphql.NewList(graphqlmultipart.Upload)},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						b, err := json.Marshal(names(p.Args))
+						return string(b), err
+					},
+				},
+			},
+		}),
+	})
+	require.NoError(t, err)
+	return &s
+}
+
+const formQuery = `query Post($title: String!, $rating: Int, $score: Float, $public: Boolean!, $tags: [String], $kind: Kind, $meta: PostMeta, $cover: Upload, $photos: [Upload]) {
+	post(title: $title, rating: $rating, score: $score, public: $public, tags: $tags, kind: $kind, meta: $meta, cover: $cover, photos: $photos)
+}`
+
+func TestHTMLForm(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	post := func(args string) string {
+		b, _ := json.Marshal(map[string]interface{}{"data": map[string]string{"post": args}})
+		return string(b)
+	}
+
+	cases := map[string]test{
+		"coerces_values": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "title", value: "Hello"},
+				formPart{name: "rating", value: "5"},
+				formPart{name: "score", value: "4.5"},
+				formPart{name: "public", value: "on"},
+				formPart{name: "tags", value: "a"},
+				formPart{name: "tags", value: "b"},
+				formPart{name: "kind", value: "NOTE"},
+				formPart{name: "meta.author", value: "me"},
+			),
+			respo: post(`{"kind":"note","meta":{"author":"me"},"public":true,"rating":5,"score":4.5,"tags":["a","b"],"title":"Hello"}`),
+		},
+		"unchecked_checkbox_and_empty_inputs": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "title", value: ""},
+				formPart{name: "rating", value: ""},
+			),
+			respo: post(`{"public":false,"title":""}`),
+		},
+		"maps_files": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "cover", file: "handler.go"},
+				formPart{name: "photos", file: "form.go"},
+				formPart{name: "photos", file: "html.go"},
+				formPart{name: "meta.attachment", file: "path.go"},
+				formPart{name: "title", value: "Files"},
+			),
+			respo: post(`{"cover":"handler.go","meta":{"attachment":"path.go"},"photos":["form.go","html.go"],"public":false,"title":"Files"}`),
+		},
+		"invalid_value": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "title", value: "Hello"},
+				formPart{name: "rating", value: "five"},
+			),
+			respo: `{"data":null,"errors":[{"message":"Variable \"$rating\" got invalid value \"five\".\nExpected type \"Int\", found \"five\".","locations":[{"line":1,"column":29}]}]}`,
+		},
+		"operations_field_is_rejected": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "operations", value: `{"query":"{ __typename }","variables":{}}`},
+				formPart{name: "map", value: `{}`},
+			),
+			respo: getJSONError(graphqlmultipart.HTMLFormFieldMessage, "operations"),
+		},
+		"map_field_after_files_is_rejected": test{
+			req: newHTMLFormRequest(t,
+				formPart{name: "cover", file: "handler.go"},
+				formPart{name: "map", value: `{"cover":["variables.cover"]}`},
+			),
+			respo: getJSONError(graphqlmultipart.HTMLFormFieldMessage, "map"),
+		},
+	}
+
+	mh := graphqlmultipart.NewHandler(
+		newFormSchema(t),
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithHTMLForm(graphqlmultipart.HTMLForm{Query: formQuery, OperationName: "Post"}),
+		graphqlmultipart.WithEarlyValidation(),
+	)
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestHTMLForm_DocumentID(t *testing.T) {
+	documents := graphqlmultipart.NewTrustedDocuments(map[string]string{"post": formQuery})
+
+	cases := map[string]string{
+		"post":    `{"data":{"post":"{\"public\":true,\"title\":\"Hello\"}"}}`,
+		"unknown": getJSONError(graphqlmultipart.UntrustedDocumentMessage, 0),
+	}
+
+	for id, respo := range cases {
+		t.Run(id, func(t *testing.T) {
+			mh := graphqlmultipart.NewHandler(
+				newFormSchema(t),
+				1*1024,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("should not have forwarded the request"))
+				}),
+				graphqlmultipart.WithHTMLForm(graphqlmultipart.HTMLForm{DocumentID: id}),
+				graphqlmultipart.WithTrustedDocuments(documents),
+			)
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, newHTMLFormRequest(t,
+				formPart{name: "title", value: "Hello"},
+				formPart{name: "public", value: "true"},
+			))
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, respo, string(body))
+		})
+	}
+}
+
+func TestHTMLForm_Origin(t *testing.T) {
+	type test struct {
+		form    graphqlmultipart.HTMLForm
+		headers map[string]string
+		respo   string
+	}
+
+	form := graphqlmultipart.HTMLForm{Query: formQuery}
+	allowed := graphqlmultipart.HTMLForm{Query: formQuery, AllowedOrigins: []string{"https://pages.example.com/"}}
+	posted := `{"data":{"post":"{\"public\":false,\"title\":\"Hello\"}"}}`
+
+	cases := map[string]test{
+		"same_origin": test{
+			form:  form,
+			respo: posted,
+		},
+		"cross_origin": test{
+			form:    form,
+			headers: map[string]string{"Origin": "https://evil.com"},
+			respo:   getJSONError(graphqlmultipart.CrossOriginFormMessage, "https://evil.com"),
+		},
+		"no_origin": test{
+			form:    form,
+			headers: map[string]string{"Origin": ""},
+			respo:   getJSONError(graphqlmultipart.CrossOriginFormMessage, "(none)"),
+		},
+		"null_origin": test{
+			form:    form,
+			headers: map[string]string{"Origin": "null"},
+			respo:   getJSONError(graphqlmultipart.CrossOriginFormMessage, "(none)"),
+		},
+		"referer": test{
+			form:    form,
+			headers: map[string]string{"Origin": "", "Referer": "http://example.com/posts/new"},
+			respo:   posted,
+		},
+		"allowed_origin": test{
+			form:    allowed,
+			headers: map[string]string{"Origin": "https://pages.example.com"},
+			respo:   posted,
+		},
+		"not_allowed_same_origin": test{
+			form:  allowed,
+			respo: getJSONError(graphqlmultipart.CrossOriginFormMessage, "http://example.com"),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			mh := graphqlmultipart.NewHandler(
+				newFormSchema(t),
+				1*1024,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("should not have forwarded the request"))
+				}),
+				graphqlmultipart.WithHTMLForm(test.form),
+				graphqlmultipart.WithCSRFPrevention(),
+			)
+
+			req := newHTMLFormRequest(t, formPart{name: "title", value: "Hello"})
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestHTMLForm_Conformance(t *testing.T) {
+	modes := map[string]graphqlmultipart.ConformanceMode{
+		"strict": graphqlmultipart.ConformanceStrict,
+		"warn":   graphqlmultipart.ConformanceWarn,
+	}
+
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			mh := graphqlmultipart.NewHandler(
+				newFormSchema(t),
+				1*1024,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("should not have forwarded the request"))
+				}),
+				graphqlmultipart.WithHTMLForm(graphqlmultipart.HTMLForm{Query: formQuery}),
+				graphqlmultipart.WithConformance(mode),
+			)
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, newHTMLFormRequest(t,
+				formPart{name: "title", value: "Hello"},
+				formPart{name: "tags", value: "a"},
+				formPart{name: "tags", value: "b"},
+				formPart{name: "photos", file: "form.go"},
+				formPart{name: "photos", file: "html.go"},
+			))
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t,
+				`{"data":{"post":"{\"photos\":[\"form.go\",\"html.go\"],\"public\":false,\"tags\":[\"a\",\"b\"],\"title\":\"Hello\"}"}}`,
+				string(body),
+			)
+		})
+	}
+}
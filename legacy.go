@@ -0,0 +1,143 @@
+package graphqlmultipart
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"strings"
+)
+
+// WithLegacyFormats makes the handler accept the formats used by clients made
+// before the v2.0.0 of the spec, they are normalized into the "operations"
+// and "map" fields:
+//
+//   - the operation sent as the "query", "variables" and "operationName"
+//     fields, instead of the "operations" field
+//   - no "map" field, with the files sent in fields named by the path of the
+//     variables they go into (like "variables.file" or "0.variables.file"),
+//     or by the name of the variable (like "file")
+//
+// Files of requests without the "map" field can only be mapped after all the
+// parts are read, so hooks and options that need the operations before the
+// files will only run after them for those requests. When there are any of
+// them, requests sending the "map" field after the files are still rejected,
+// as they are not in a legacy format
+func WithLegacyFormats() Option {
+	return func(m *MultipartHandler) {
+		m.legacyFormats = true
+	}
+}
+
+// isLegacyForm checks if the form has the operations in any format, but not
+// both the "operations" and "map" fields the spec requires
+func isLegacyForm(form *multipart.Form) bool {
+	return !hasFields(form, "operations", "map") && (hasFields(form, "operations") || hasFields(form, "query"))
+}
+
+// normalizeLegacyForm fills the "operations" and "map" fields of the form from
+// the legacy formats, and adds the variables legacy clients left out for the
+// mapped files
+func normalizeLegacyForm(form *multipart.Form) {
+	if !hasFields(form, "operations") && hasFields(form, "query") {
+		op := map[string]interface{}{"query": form.Value["query"][0]}
+
+		vars := make(map[string]interface{})
+		if vs, ok := form.Value["variables"]; ok && strings.TrimSpace(vs[0]) != "" {
+			if err := json.Unmarshal([]byte(vs[0]), &vars); err != nil {
+				// left to parseRequest to report as a invalid "operations"
+				op["variables"] = vs[0]
+			}
+		}
+		if _, ok := op["variables"]; !ok {
+			op["variables"] = vars
+		}
+
+		if vs, ok := form.Value["operationName"]; ok {
+			op["operationName"] = vs[0]
+		}
+
+		b, _ := json.Marshal(op)
+		form.Value["operations"] = []string{string(b)}
+	}
+
+	if !hasFields(form, "operations") {
+		return
+	}
+
+	// numbers are kept as sent, as float64 could change them
+	var ops interface{}
+	d := json.NewDecoder(strings.NewReader(form.Value["operations"][0]))
+	d.UseNumber()
+	if err := d.Decode(&ops); err != nil {
+		return
+	}
+
+	_, batching := ops.([]interface{})
+	fileMap := make(map[string][]string, len(form.File))
+	if vs, ok := form.Value["map"]; ok {
+		if err := json.Unmarshal([]byte(vs[0]), &fileMap); err != nil {
+			return
+		}
+	} else {
+		for f := range form.File {
+			fileMap[f] = []string{legacyPath(f, batching)}
+		}
+
+		b, _ := json.Marshal(fileMap)
+		form.Value["map"] = []string{string(b)}
+	}
+
+	for _, ps := range fileMap {
+		for _, raw := range ps {
+			if p, err := parseMapPath(raw); err == nil {
+				addPlaceholder(ops, p, batching)
+			}
+		}
+	}
+
+	b, _ := json.Marshal(ops)
+	form.Value["operations"] = []string{string(b)}
+}
+
+// legacyPath is the path of the map for a file field of a legacy form, which
+// is named by the path it goes into, or by the name of its variable
+func legacyPath(name string, batching bool) string {
+	if p, err := parseMapPath(name); err == nil {
+		ss := p.segments
+		if _, ok := index(ss[0]); ok && batching {
+			ss = ss[1:]
+		}
+
+		if len(ss) > 1 && mapRoots[ss[0]] {
+			return name
+		}
+	}
+	return "variables." + name
+}
+
+// addPlaceholder adds a null variable for paths like "variables.file" or
+// "0.variables.file", as legacy clients removed the variables of the files
+func addPlaceholder(ops interface{}, p mapPath, batching bool) {
+	ss := p.segments
+	if batching {
+		vs, ok := ops.([]interface{})
+		i, isIndex := index(ss[0])
+		if !ok || !isIndex || i >= len(vs) {
+			return
+		}
+		ops, ss = vs[i], ss[1:]
+	}
+
+	op, ok := ops.(map[string]interface{})
+	if !ok || len(ss) != 2 || ss[0] != "variables" {
+		return
+	}
+
+	vars, ok := op["variables"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if _, ok := vars[ss[1]]; !ok {
+		vars[ss[1]] = nil
+	}
+}
@@ -0,0 +1,177 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacyFormats(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	uploaded := `{"data":{"upload":{"filename":"handler.go"}}}`
+
+	cases := map[string]test{
+		"query_and_variables_fields": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"query":     `query ($file: Upload){ upload(file:$file){filename} }`,
+					"variables": `{"file":null}`,
+					"map":       `{"file":["variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: uploaded,
+		},
+		"files_keyed_by_variable_path": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}}`,
+				},
+				map[string]string{"variables.file": "handler.go"},
+			),
+			respo: uploaded,
+		},
+		"files_keyed_by_variable_name": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"query":         `query Upload($file: Upload){ upload(file:$file){filename} }`,
+					"operationName": "Upload",
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: uploaded,
+		},
+		"batch_files_keyed_by_path": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}},
+						{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}}
+					]`,
+				},
+				map[string]string{"0.variables.file": "handler.go", "1.variables.file": "handler.go"},
+			),
+			respo: `[` + uploaded + `,` + uploaded + `]`,
+		},
+		"files_keyed_by_pointer": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}}`,
+				},
+				map[string]string{"/variables/file": "handler.go"},
+			),
+			respo: uploaded,
+		},
+		"spec_requests_are_not_changed": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}}`,
+					"map":        `{"file":["variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables.file", "file"),
+		},
+		"invalid_variables_field": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"query":     `query ($file: Upload){ upload(file:$file){filename} }`,
+					"variables": `[`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidOperationsFieldMessage),
+		},
+		"no_operations": test{
+			req:   newFileUploadRequest(map[string]string{}, map[string]string{"file": "handler.go"}),
+			respo: getJSONError(graphqlmultipart.OperationsFieldMissingMessage),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler(graphqlmultipart.WithLegacyFormats()).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestLegacyFormats_BeforeFiles(t *testing.T) {
+	mh := newPolicyHandler(
+		graphqlmultipart.WithLegacyFormats(),
+		graphqlmultipart.WithEarlyValidation(),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"query": `query ($file: Upload){ upload(file:$file){filename} }`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go"}}}`, string(body))
+
+	resp = httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"query": `query ($file: Upload){ upload(file:$file){filename} }`,
+			"map":   `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ = ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, `{"data":{"upload":{"filename":"handler.go"}}}`, string(body))
+}
+
+func TestLegacyFormats_MapAfterFiles(t *testing.T) {
+	mh := newPolicyHandler(
+		graphqlmultipart.WithLegacyFormats(),
+		graphqlmultipart.WithEarlyValidation(),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newHTMLFormRequest(t,
+		formPart{name: "operations", value: `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}`},
+		formPart{name: "file", file: "handler.go"},
+		formPart{name: "map", value: `{"file":["variables.file"]}`},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, getJSONError(graphqlmultipart.FilesBeforeOperationsMessage), string(body))
+}
+
+func TestLegacyFormats_Disabled(t *testing.T) {
+	cases := map[string]string{
+		"query_field": getJSONError(graphqlmultipart.OperationsFieldMissingMessage),
+		"no_map":      getJSONError(graphqlmultipart.MapFieldMissingMessage),
+	}
+
+	for name, respo := range cases {
+		t.Run(name, func(t *testing.T) {
+			params := map[string]string{
+				"query": `query ($file: Upload){ upload(file:$file){filename} }`,
+			}
+			if name == "no_map" {
+				params = map[string]string{
+					"operations": `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{}}`,
+				}
+			}
+
+			resp := httptest.NewRecorder()
+			newPolicyHandler().ServeHTTP(resp, newFileUploadRequest(params, map[string]string{"variables.file": "handler.go"}))
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, respo, string(body))
+		})
+	}
+}
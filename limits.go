@@ -0,0 +1,174 @@
+package graphqlmultipart
+
+import (
+	"fmt"
+	"mime/multipart"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var (
+	// DepthLimitMessage is shown when a operation has more nested fields than allowed
+	DepthLimitMessage = "Operation %[1]d has depth %[2]d, but the maximum allowed is %[3]d"
+
+	// ComplexityLimitMessage is shown when the complexity of a operation is higher than allowed
+	ComplexityLimitMessage = "Operation %[1]d has complexity %[2]d, but the maximum allowed is %[3]d"
+
+	// AliasLimitMessage is shown when a operation uses more aliases than allowed
+	AliasLimitMessage = "Operation %[1]d has %[2]d aliases, but the maximum allowed is %[3]d"
+)
+
+// Limits restricts how expensive the operations can be, they are checked
+// after the files are injected and before the operation is executed. Zero
+// means no limit
+type Limits struct {
+	// MaxDepth is the maximum nesting of fields, the fields of the operation
+	// itself have depth 1
+	MaxDepth int
+
+	// MaxComplexity is the maximum complexity of a operation, every field
+	// counts as 1, plus UploadWeight for every file it receives
+	MaxComplexity int
+
+	// UploadWeight is how much each file received by a field adds to its
+	// complexity
+	UploadWeight int
+
+	// MaxAliases is the maximum number of aliased fields
+	MaxAliases int
+}
+
+// WithLimits sets the Limits of the operations
+func WithLimits(l Limits) Option {
+	return func(m *MultipartHandler) {
+		m.limits = l
+	}
+}
+
+// operationCost is the measures of a operation checked by the Limits
+type operationCost struct {
+	depth      int
+	complexity int
+	aliases    int
+}
+
+// checkLimits measures the operation and checks it against the Limits,
+// returning the outcome and error of the first limit exceeded
+func (m MultipartHandler) checkLimits(i int, doc *ast.Document, def *ast.OperationDefinition, vars map[string]interface{}) (string, error) {
+	l := m.limits
+	if l.MaxDepth <= 0 && l.MaxComplexity <= 0 && l.MaxAliases <= 0 {
+		return OutcomeSuccess, nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, d := range doc.Definitions {
+		if f, ok := d.(*ast.FragmentDefinition); ok && f.Name != nil {
+			fragments[f.Name.Value] = f
+		}
+	}
+
+	c := measure(def.SelectionSet, 1, fragments, vars, l.UploadWeight, make(map[string]bool))
+
+	if l.MaxDepth > 0 && c.depth > l.MaxDepth {
+		return OutcomeDepthLimitExceeded, fmt.Errorf(DepthLimitMessage, i, c.depth, l.MaxDepth)
+	}
+
+	if l.MaxComplexity > 0 && c.complexity > l.MaxComplexity {
+		return OutcomeComplexityLimitExceeded, fmt.Errorf(ComplexityLimitMessage, i, c.complexity, l.MaxComplexity)
+	}
+
+	if l.MaxAliases > 0 && c.aliases > l.MaxAliases {
+		return OutcomeAliasLimitExceeded, fmt.Errorf(AliasLimitMessage, i, c.aliases, l.MaxAliases)
+	}
+
+	return OutcomeSuccess, nil
+}
+
+// measure walks the selection set, following the fragments, the ones being
+// visited are skipped so cycles don't loop forever
+func measure(ss *ast.SelectionSet, depth int, fragments map[string]*ast.FragmentDefinition, vars map[string]interface{}, uploadWeight int, visiting map[string]bool) operationCost {
+	c := operationCost{}
+	if ss == nil {
+		return c
+	}
+
+	add := func(s operationCost) {
+		if s.depth > c.depth {
+			c.depth = s.depth
+		}
+		c.complexity += s.complexity
+		c.aliases += s.aliases
+	}
+
+	for _, s := range ss.Selections {
+		switch s := s.(type) {
+		case *ast.Field:
+			if s.Alias != nil {
+				c.aliases++
+			}
+
+			uploads := 0
+			for _, a := range s.Arguments {
+				uploads += countUploads(a.Value, vars)
+			}
+
+			add(operationCost{depth: depth, complexity: 1 + uploads*uploadWeight})
+			add(measure(s.SelectionSet, depth+1, fragments, vars, uploadWeight, visiting))
+		case *ast.InlineFragment:
+			add(measure(s.SelectionSet, depth, fragments, vars, uploadWeight, visiting))
+		case *ast.FragmentSpread:
+			f, ok := fragments[s.Name.Value]
+			if !ok || visiting[s.Name.Value] {
+				continue
+			}
+
+			visiting[s.Name.Value] = true
+			add(measure(f.SelectionSet, depth, fragments, vars, uploadWeight, visiting))
+			delete(visiting, s.Name.Value)
+		}
+	}
+
+	return c
+}
+
+// countUploads counts the files a argument receives through its variables
+func countUploads(v ast.Value, vars map[string]interface{}) int {
+	switch v := v.(type) {
+	case *ast.Variable:
+		return countFiles(vars[v.Name.Value])
+	case *ast.ListValue:
+		n := 0
+		for _, e := range v.Values {
+			n += countUploads(e, vars)
+		}
+		return n
+	case *ast.ObjectValue:
+		n := 0
+		for _, f := range v.Fields {
+			n += countUploads(f.Value, vars)
+		}
+		return n
+	}
+	return 0
+}
+
+// countFiles counts the files injected into a value of the variables
+func countFiles(v interface{}) int {
+	switch v := v.(type) {
+	case *multipart.FileHeader:
+		return 1
+	case map[string]interface{}:
+		n := 0
+		for _, e := range v {
+			n += countFiles(e)
+		}
+		return n
+	case []interface{}:
+		n := 0
+		for _, e := range v {
+			n += countFiles(e)
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	type test struct {
+		limits graphqlmultipart.Limits
+		req    *http.Request
+		respo  string
+	}
+
+	// depth 3, complexity 5 plus 2 uploads
+	special := func() *http.Request {
+		return newFileUploadRequest(
+			map[string]string{
+				"operations": `{"query":"query($input:SpecialUploadInput) { specialUploads(input: $input){ filename, headers { name, values } } }","variables":{"input":{"name":"a","files":[null,null]}}}`,
+				"map":        `{"file":["variables.input.files.0","variables.input.files.1"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		)
+	}
+
+	cases := map[string]test{
+		"depth": test{
+			limits: graphqlmultipart.Limits{MaxDepth: 2},
+			req:    special(),
+			respo:  getJSONError(graphqlmultipart.DepthLimitMessage, 0, 3, 2),
+		},
+		"depth_through_fragments": test{
+			limits: graphqlmultipart.Limits{MaxDepth: 2},
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { ...F } fragment F on RootQuery { ... on RootQuery { upload(file: $file){ headers { name } } } }","variables":{"file":null}}`,
+					"map":        `{"file":["variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.DepthLimitMessage, 0, 3, 2),
+		},
+		"complexity_weighted_by_uploads": test{
+			limits: graphqlmultipart.Limits{MaxComplexity: 24, UploadWeight: 10},
+			req:    special(),
+			respo:  getJSONError(graphqlmultipart.ComplexityLimitMessage, 0, 25, 24),
+		},
+		"inside_limits": test{
+			limits: graphqlmultipart.Limits{MaxDepth: 2, MaxComplexity: 22, UploadWeight: 10, MaxAliases: 1},
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($input:SpecialUploadInput) { s: specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null,null]}}}`,
+					"map":        `{"file":["variables.input.files.0","variables.input.files.1"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `{"data":{"s":[{"filename":"handler.go"},{"filename":"handler.go"}]}}`,
+		},
+		"aliases": test{
+			limits: graphqlmultipart.Limits{MaxAliases: 1},
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { a: upload(file: $file){ b: filename } }","variables":{"file":null}}`,
+					"map":        `{"file":["variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.AliasLimitMessage, 0, 2, 1),
+		},
+		"batching_isolates_errors": test{
+			limits: graphqlmultipart.Limits{MaxDepth: 1},
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"query":"query { __typename }","variables":{}},
+						{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}
+					]`,
+					"map": `{"file":["1.variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `[
+				{"data":{"__typename":"RootQuery"}},
+				` + getJSONError(graphqlmultipart.DepthLimitMessage, 1, 2, 1) + `
+			]`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler(graphqlmultipart.WithLimits(test.limits)).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package graphqlmultipart
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a map safe for concurrent use that keeps only the most recently used
+// entries
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	entries *list.List
+	items   map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// newLRU creates a lru that keeps up to size entries, with a size of zero or
+// less it keeps none
+func newLRU(size int) *lru {
+	if size < 0 {
+		size = 0
+	}
+
+	return &lru{
+		size:    size,
+		entries: list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.entries.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *lru) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size == 0 {
+		return
+	}
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.entries.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.entries.PushFront(&lruEntry{key: key, value: value})
+	for c.entries.Len() > c.size {
+		e := c.entries.Back()
+		c.entries.Remove(e)
+		delete(c.items, e.Value.(*lruEntry).key)
+	}
+}
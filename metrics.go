@@ -0,0 +1,245 @@
+package graphqlmultipart
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes used to classify the handled requests, each one relates to one of
+// the *Message errors of the package, or to a rejection made by the Hooks
+const (
+	OutcomeSuccess                    = "success"
+	OutcomeFailedToParseForm          = "failed_to_parse_form"
+	OutcomeOperationsFieldMissing     = "operations_field_missing"
+	OutcomeMapFieldMissing            = "map_field_missing"
+	OutcomeInvalidMapField            = "invalid_map_field"
+	OutcomeInvalidOperationsField     = "invalid_operations_field"
+	OutcomeMissingFile                = "missing_file"
+	OutcomeInvalidMapPath             = "invalid_map_path"
+	OutcomeRejected                   = "rejected"
+	OutcomeInvalidChecksum            = "invalid_checksum"
+	OutcomeChecksumMismatch           = "checksum_mismatch"
+	OutcomeBatchingDisabled           = "batching_disabled"
+	OutcomeBatchTooLarge              = "batch_too_large"
+	OutcomeOperationTypeNotAllowed    = "operation_type_not_allowed"
+	OutcomeInvalidReference           = "invalid_reference"
+	OutcomeTransactionFailed          = "transaction_failed"
+	OutcomeCSRFPrevented              = "csrf_prevented"
+	OutcomeUnauthorized               = "unauthorized"
+	OutcomeFilesBeforeOperations      = "files_before_operations"
+	OutcomeInvalidQuery               = "invalid_query"
+	OutcomeUploadTypeMismatch         = "upload_type_mismatch"
+	OutcomeInlineUpload               = "inline_upload"
+	OutcomeNonConformant              = "non_conformant"
+	OutcomePersistedQueryNotFound     = "persisted_query_not_found"
+	OutcomePersistedQueryNotSupported = "persisted_query_not_supported"
+	OutcomeInvalidPersistedQuery      = "invalid_persisted_query"
+	OutcomeUntrustedDocument          = "untrusted_document"
+	OutcomeDepthLimitExceeded         = "depth_limit_exceeded"
+	OutcomeComplexityLimitExceeded    = "complexity_limit_exceeded"
+	OutcomeAliasLimitExceeded         = "alias_limit_exceeded"
+	OutcomeSchemaNotFound             = "schema_not_found"
+)
+
+// Metrics receives the measurements made by the MultipartHandler while
+// handling "multipart/form-data" requests
+type Metrics interface {
+	// RequestHandled is called once per request with its Outcome*
+	RequestHandled(outcome string)
+	// UploadStarted is called when a multipart request starts to be handled
+	UploadStarted()
+	// UploadFinished is called when a multipart request was fully handled
+	UploadFinished()
+	// FormParsed is called with the time spent parsing the multipart form
+	FormParsed(d time.Duration)
+	// FileReceived is called with the size of every file received
+	FileReceived(size int64)
+	// FilesReceived is called with the number of files of a request
+	FilesReceived(n int)
+	// BatchReceived is called with the number of operations of batched requests
+	BatchReceived(n int)
+	// OperationExecuted is called with the time spent executing a operation
+	OperationExecuted(d time.Duration)
+	// DocumentCacheLookup is called every time a document is looked up in the
+	// cache enabled by WithDocumentCache, hit tells if it was found
+	DocumentCacheLookup(hit bool)
+}
+
+// WithMetrics sets the Metrics that will receive the handler measurements,
+// by default they are discarded
+func WithMetrics(metrics Metrics) Option {
+	return func(m *MultipartHandler) {
+		m.metrics = metrics
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RequestHandled(string)           {}
+func (noopMetrics) UploadStarted()                  {}
+func (noopMetrics) UploadFinished()                 {}
+func (noopMetrics) FormParsed(time.Duration)        {}
+func (noopMetrics) FileReceived(int64)              {}
+func (noopMetrics) FilesReceived(int)               {}
+func (noopMetrics) BatchReceived(int)               {}
+func (noopMetrics) OperationExecuted(time.Duration) {}
+func (noopMetrics) DocumentCacheLookup(bool)        {}
+
+// PrometheusMetrics is a Metrics that exposes the measurements as a
+// prometheus.Collector
+type PrometheusMetrics struct {
+	requests        *prometheus.CounterVec
+	inFlight        prometheus.Gauge
+	parseDuration   prometheus.Histogram
+	fileSize        prometheus.Histogram
+	filesPerRequest prometheus.Histogram
+	batches         prometheus.Counter
+	batchOperations prometheus.Counter
+	execDuration    prometheus.Histogram
+	documentCache   *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics, all metrics will be
+// prefixed with the namespace informed
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	const subsystem = "multipart"
+	return &PrometheusMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of multipart requests handled, by outcome",
+		}, []string{"outcome"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "uploads_in_flight",
+			Help:      "Number of multipart requests being handled",
+		}),
+		parseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "parse_duration_seconds",
+			Help:      "Time spent parsing the multipart form",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		fileSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "file_size_bytes",
+			Help:      "Size of the uploaded files",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 10),
+		}),
+		filesPerRequest: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "files_per_request",
+			Help:      "Number of files uploaded per request",
+			Buckets:   []float64{0, 1, 2, 5, 10, 20, 50, 100},
+		}),
+		batches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "batches_total",
+			Help:      "Number of batched requests handled",
+		}),
+		batchOperations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "batch_operations_total",
+			Help:      "Number of operations received inside batched requests",
+		}),
+		execDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "execution_duration_seconds",
+			Help:      "Time spent executing each operation",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		documentCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "document_cache_lookups_total",
+			Help:      "Number of lookups in the cache of parsed documents, by result (hit or miss)",
+		}, []string{"result"}),
+	}
+}
+
+func (p *PrometheusMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		p.requests,
+		p.inFlight,
+		p.parseDuration,
+		p.fileSize,
+		p.filesPerRequest,
+		p.batches,
+		p.batchOperations,
+		p.execDuration,
+		p.documentCache,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (p *PrometheusMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range p.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (p *PrometheusMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range p.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// RequestHandled implements Metrics
+func (p *PrometheusMetrics) RequestHandled(outcome string) {
+	p.requests.WithLabelValues(outcome).Inc()
+}
+
+// UploadStarted implements Metrics
+func (p *PrometheusMetrics) UploadStarted() {
+	p.inFlight.Inc()
+}
+
+// UploadFinished implements Metrics
+func (p *PrometheusMetrics) UploadFinished() {
+	p.inFlight.Dec()
+}
+
+// FormParsed implements Metrics
+func (p *PrometheusMetrics) FormParsed(d time.Duration) {
+	p.parseDuration.Observe(d.Seconds())
+}
+
+// FileReceived implements Metrics
+func (p *PrometheusMetrics) FileReceived(size int64) {
+	p.fileSize.Observe(float64(size))
+}
+
+// FilesReceived implements Metrics
+func (p *PrometheusMetrics) FilesReceived(n int) {
+	p.filesPerRequest.Observe(float64(n))
+}
+
+// BatchReceived implements Metrics
+func (p *PrometheusMetrics) BatchReceived(n int) {
+	p.batches.Inc()
+	p.batchOperations.Add(float64(n))
+}
+
+// OperationExecuted implements Metrics
+func (p *PrometheusMetrics) OperationExecuted(d time.Duration) {
+	p.execDuration.Observe(d.Seconds())
+}
+
+// DocumentCacheLookup implements Metrics
+func (p *PrometheusMetrics) DocumentCacheLookup(hit bool) {
+	if hit {
+		p.documentCache.WithLabelValues("hit").Inc()
+		return
+	}
+	p.documentCache.WithLabelValues("miss").Inc()
+}
@@ -0,0 +1,96 @@
+package graphqlmultipart_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics_MeasuresRequests(t *testing.T) {
+	pm := graphqlmultipart.NewPrometheusMetrics("test")
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(pm))
+
+	mh := graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithMetrics(pm),
+	)
+
+	reqs := []*http.Request{
+		newFileUploadRequest(make(map[string]string), make(map[string]string)),
+		newFileUploadRequest(
+			map[string]string{
+				"operations": `[
+					{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}},
+					{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}
+				]`,
+				"map": `{"file":["0.variables.file","1.variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		),
+		newFileUploadRequest(
+			map[string]string{
+				"operations": `{"query":"query hero{id}","variables":{}}`,
+				"map":        `{"file":["variables.file"]}`,
+			},
+			map[string]string{"file": "handler.go"},
+		),
+	}
+
+	for _, r := range reqs {
+		mh.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	expected := `
+		# HELP test_multipart_requests_total Number of multipart requests handled, by outcome
+		# TYPE test_multipart_requests_total counter
+		test_multipart_requests_total{outcome="invalid_map_path"} 1
+		test_multipart_requests_total{outcome="operations_field_missing"} 1
+		test_multipart_requests_total{outcome="success"} 1
+		# HELP test_multipart_uploads_in_flight Number of multipart requests being handled
+		# TYPE test_multipart_uploads_in_flight gauge
+		test_multipart_uploads_in_flight 0
+		# HELP test_multipart_batches_total Number of batched requests handled
+		# TYPE test_multipart_batches_total counter
+		test_multipart_batches_total 1
+		# HELP test_multipart_batch_operations_total Number of operations received inside batched requests
+		# TYPE test_multipart_batch_operations_total counter
+		test_multipart_batch_operations_total 2
+	`
+	require.NoError(t, promtestutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"test_multipart_requests_total",
+		"test_multipart_uploads_in_flight",
+		"test_multipart_batches_total",
+		"test_multipart_batch_operations_total",
+	))
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+
+	samples := make(map[string]uint64)
+	for _, mf := range mfs {
+		if h := mf.GetMetric()[0].GetHistogram(); h != nil {
+			samples[mf.GetName()] = h.GetSampleCount()
+		}
+	}
+
+	require.Equal(t, uint64(3), samples["test_multipart_parse_duration_seconds"])
+	require.Equal(t, uint64(3), samples["test_multipart_files_per_request"])
+	require.Equal(t, uint64(2), samples["test_multipart_file_size_bytes"])
+	require.Equal(t, uint64(2), samples["test_multipart_execution_duration_seconds"])
+}
@@ -0,0 +1,119 @@
+package graphqlmultipart
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// mapPath is a path of the "map" field, parsed into its segments. Paths are
+// written with dots, as "variables.input.files.0", where "\." and "\\" escape
+// a dot or backslash that is part of a key, or as RFC 6901 JSON Pointers, as
+// "/variables/input/files/0"
+type mapPath struct {
+	raw      string
+	segments []string
+}
+
+var errInvalidPath = errors.New("invalid path")
+
+// mapRoots are the fields of a operation the files can be mapped into
+var mapRoots = map[string]bool{"variables": true, "extensions": true}
+
+// parseMapPath parses a path of the map in any of the supported syntaxes
+func parseMapPath(raw string) (mapPath, error) {
+	var ss []string
+	var err error
+	if strings.HasPrefix(raw, "/") {
+		ss, err = parsePointer(raw)
+	} else {
+		ss, err = parseDotted(raw)
+	}
+
+	if err != nil {
+		return mapPath{}, err
+	}
+	return mapPath{raw: raw, segments: ss}, nil
+}
+
+// parseDotted splits a path on the dots that are not escaped
+func parseDotted(raw string) ([]string, error) {
+	ss := make([]string, 0)
+	var b strings.Builder
+	escaped := false
+	for _, c := range raw {
+		switch {
+		case escaped:
+			if c != '.' && c != '\\' {
+				return nil, errInvalidPath
+			}
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '.':
+			if b.Len() == 0 {
+				return nil, errInvalidPath
+			}
+			ss = append(ss, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	if escaped || b.Len() == 0 {
+		return nil, errInvalidPath
+	}
+	return append(ss, b.String()), nil
+}
+
+// parsePointer splits a JSON Pointer into its reference tokens
+func parsePointer(raw string) ([]string, error) {
+	ss := strings.Split(raw[1:], "/")
+	for i, s := range ss {
+		for j := 0; j < len(s); j++ {
+			if s[j] == '~' && (j+1 == len(s) || (s[j+1] != '0' && s[j+1] != '1')) {
+				return nil, errInvalidPath
+			}
+		}
+		ss[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+	}
+	return ss, nil
+}
+
+// relative returns the segments after the prefix, if the path starts with it
+func (p mapPath) relative(prefix ...string) ([]string, bool) {
+	if len(p.segments) <= len(prefix) {
+		return nil, false
+	}
+
+	for i, s := range prefix {
+		if p.segments[i] != s {
+			return nil, false
+		}
+	}
+	return p.segments[len(prefix):], true
+}
+
+// key is the same for paths pointing to the same value, whatever their syntax
+func (p mapPath) key() string {
+	ss := make([]string, len(p.segments))
+	for i, s := range p.segments {
+		ss[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+	}
+	return "/" + strings.Join(ss, "/")
+}
+
+func (p mapPath) String() string {
+	return p.raw
+}
+
+// index reads a segment as the index of a list
+func index(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || strconv.Itoa(n) != s {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,101 @@
+package graphqlmultipart_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapPaths(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	special := `{"query":"query($input:SpecialUploadInput) { specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null,null]}}}`
+	cases := map[string]test{
+		"json_pointer": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"a":["/variables/input/files/0"],"b":["variables.input.files.1"]}`},
+				map[string]string{"a": "handler.go", "b": "README.md"},
+			),
+			respo: `{"data":{"specialUploads":[{"filename":"handler.go"},{"filename":"README.md"}]}}`,
+		},
+		"json_pointer_batching": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}]`,
+					"map":        `{"file":["/0/variables/file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `[{"data":{"upload":{"filename":"handler.go"}}}]`,
+		},
+		"escaped_dot": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input\\.files.0"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, `variables.input\.files.0`, "file"),
+		},
+		"invalid_escape": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.in\\put.files.0"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, `variables.in\put.files.0`, "file"),
+		},
+		"empty_segment": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables..input"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables..input", "file"),
+		},
+		"invalid_pointer_escape": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["/variables/input~2/files/0"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "/variables/input~2/files/0", "file"),
+		},
+		"index_with_leading_zero": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input.files.01"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables.input.files.01", "file"),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler().ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestMapPaths_ConflictsAcrossSyntaxes(t *testing.T) {
+	resp := httptest.NewRecorder()
+	newPolicyHandler(graphqlmultipart.WithConformance(graphqlmultipart.ConformanceStrict)).ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}`,
+			"map":        `{"a":["variables.file"],"b":["/variables/file"]}`,
+		},
+		map[string]string{"a": "handler.go", "b": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	m := fmt.Sprintf(graphqlmultipart.ConflictingMapPathMessage, "/variables/file", "a, b")
+	require.JSONEq(t, `{"data":null,"errors":[{"message":`+strconv.Quote(m)+`,"locations":[]}]}`, string(body))
+}
@@ -0,0 +1,46 @@
+package graphqlmultipart
+
+var (
+	// BatchingDisabledMessage is shown when the operations field is a array, but batching is disabled
+	BatchingDisabledMessage = "Batching is not allowed, field \"operations\" must be a object"
+
+	// BatchTooLargeMessage is shown when there are more operations in the batch than allowed
+	BatchTooLargeMessage = "Batch has %[1]d operations, but only %[2]d are allowed"
+
+	// OperationTypeNotAllowedMessage is shown when files are sent to a operation that is not a mutation
+	OperationTypeNotAllowedMessage = "Files can only be uploaded to mutations, but operation %[1]d is a %[2]s"
+)
+
+// WithoutBatching makes the handler reject requests with a array of operations
+func WithoutBatching() Option {
+	return func(m *MultipartHandler) {
+		m.disableBatching = true
+	}
+}
+
+// WithMaxBatchSize limits how many operations can be sent in a batched
+// request, zero means no limit
+func WithMaxBatchSize(n int) Option {
+	return func(m *MultipartHandler) {
+		m.maxBatchSize = n
+	}
+}
+
+// WithUploadsOnlyForMutations makes the handler reject the operations that
+// receive files, but are not mutations
+func WithUploadsOnlyForMutations() Option {
+	return func(m *MultipartHandler) {
+		m.uploadsOnlyForMutations = true
+	}
+}
+
+// operationType finds the type of the operation that will be executed, it
+// returns false if it can't be found, leaving to the execution to report it
+func operationType(query, operationName string) (string, bool) {
+	op := parseOperation(query, operationName)
+	if op == nil {
+		return "", false
+	}
+
+	return op.Operation, true
+}
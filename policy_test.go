@@ -0,0 +1,116 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyHandler(opts ...graphqlmultipart.Option) http.Handler {
+	return graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		opts...,
+	)
+}
+
+func newBatchRequest(n int) *http.Request {
+	ops := make([]string, n)
+	paths := make([]string, n)
+	for i := range ops {
+		ops[i] = `{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}}`
+		paths[i] = `"` + strconv.Itoa(i) + `.variables.file"`
+	}
+
+	return newFileUploadRequest(
+		map[string]string{
+			"operations": "[" + strings.Join(ops, ",") + "]",
+			"map":        `{"file":[` + strings.Join(paths, ",") + `]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	)
+}
+
+func TestPolicy_Batching(t *testing.T) {
+	type test struct {
+		handler http.Handler
+		req     *http.Request
+		respo   string
+	}
+
+	cases := map[string]test{
+		"disabled": test{
+			handler: newPolicyHandler(graphqlmultipart.WithoutBatching()),
+			req:     newBatchRequest(1),
+			respo:   "[" + getJSONError(graphqlmultipart.BatchingDisabledMessage) + "]",
+		},
+		"disabled_allows_single": test{
+			handler: newPolicyHandler(graphqlmultipart.WithoutBatching()),
+			req:     newSimpleUploadRequest(),
+			respo:   `{"data":{"upload":{"filename":"handler.go","size":` + handlerSize(t) + `}}}`,
+		},
+		"too_large": test{
+			handler: newPolicyHandler(graphqlmultipart.WithMaxBatchSize(2)),
+			req:     newBatchRequest(3),
+			respo:   "[" + getJSONError(graphqlmultipart.BatchTooLargeMessage, 3, 2) + "]",
+		},
+		"inside_limit": test{
+			handler: newPolicyHandler(graphqlmultipart.WithMaxBatchSize(2)),
+			req:     newBatchRequest(2),
+			respo: `[
+				{"data":{"upload":{"filename":"handler.go"}}},
+				{"data":{"upload":{"filename":"handler.go"}}}
+			]`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			test.handler.ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestPolicy_UploadsOnlyForMutations(t *testing.T) {
+	mh := newPolicyHandler(graphqlmultipart.WithUploadsOnlyForMutations())
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `[
+				{"query":"query ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}},
+				{"query":"mutation ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null}},
+				{"query":"query A { upload { filename } } mutation B ($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"B"}
+			]`,
+			"map": `{"file":["0.variables.file","1.variables.file","2.variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.JSONEq(t, `[
+		`+getJSONError(graphqlmultipart.OperationTypeNotAllowedMessage, 0, "query")+`,
+		{"data":{"upload":{"filename":"handler.go"}}},
+		{"data":{"upload":{"filename":"handler.go"}}}
+	]`, string(body))
+}
+
+func handlerSize(t *testing.T) string {
+	b, err := ioutil.ReadFile("handler.go")
+	require.NoError(t, err)
+	return strconv.Itoa(len(b))
+}
@@ -0,0 +1,131 @@
+package graphqlmultipart
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// referenceKey is the key of a object used as a variable value to reference
+// the result of a previous operation of the batch, GraphQL names can't start
+// with "$", so it will never be a actual input object
+const referenceKey = "$ref"
+
+// InvalidReferenceMessage is shown when a variable references a result that
+// can't be used, like the one of a later operation, or a path that does not
+// exist in it
+var InvalidReferenceMessage = "Reference \"%[1]s\" is not valid, it must be the path of a value in the result of a previous operation (e.g.: {\"$ref\":\"0.data.createAlbum.id\"})"
+
+// reference returns the path of the value, if it's a reference
+func reference(v interface{}) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+
+	p, ok := m[referenceKey].(string)
+	return p, ok
+}
+
+// hasReferences checks if any of the variables references a previous result
+func hasReferences(v interface{}) bool {
+	if _, ok := reference(v); ok {
+		return true
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, e := range v {
+			if hasReferences(e) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if hasReferences(e) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveReferences replaces the references in the variables with the values
+// of the results of the operations before the operation i
+func resolveReferences(v interface{}, i int, results []*graphql.Result) (interface{}, error) {
+	if p, ok := reference(v); ok {
+		r, ok := lookupResult(p, i, results)
+		if !ok {
+			return nil, fmt.Errorf(InvalidReferenceMessage, p)
+		}
+		return r, nil
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			r, err := resolveReferences(e, i, results)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	case []interface{}:
+		for k, e := range v {
+			r, err := resolveReferences(e, i, results)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	}
+
+	return v, nil
+}
+
+// lookupResult finds the value of a path as "0.data.field.0.id" in the
+// results of the operations before the operation i
+func lookupResult(path string, i int, results []*graphql.Result) (interface{}, bool) {
+	ps := strings.Split(path, ".")
+	index, err := strconv.Atoi(ps[0])
+	if err != nil || index < 0 || index >= i || results[index] == nil {
+		return nil, false
+	}
+
+	// the result is read as its JSON would be, so the paths are the same the
+	// client sees in the response
+	b, err := json.Marshal(results[index])
+	if err != nil {
+		return nil, false
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, false
+	}
+
+	for _, p := range ps[1:] {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			e, ok := c[p]
+			if !ok {
+				return nil, false
+			}
+			v = e
+		case []interface{}:
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 || n >= len(c) {
+				return nil, false
+			}
+			v = c[n]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, v != nil
+}
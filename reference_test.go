@@ -0,0 +1,114 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newAlbumSchema(t *testing.T) *graphql.Schema {
+	album := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Album",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+
+	photo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Photo",
+		Fields: graphql.Fields{
+			"albumId":  &graphql.Field{Type: graphql.ID},
+			"filename": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"ok": &graphql.Field{Type: graphql.Boolean}},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createAlbum": &graphql.Field{
+					Type: album,
+					Args: graphql.FieldConfigArgument{
+						"name": &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return map[string]interface{}{"id": "album-" + p.Args["name"].(string)}, nil
+					},
+				},
+				"addPhoto": &graphql.Field{
+					Type: photo,
+					Args: graphql.FieldConfigArgument{
+						"albumId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+						"photo":   &graphql.ArgumentConfig{Type: graphqlmultipart.Upload},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return map[string]interface{}{
+							"albumId":  p.Args["albumId"],
+							"filename": p.Args["photo"].(*multipart.FileHeader).Filename,
+						}, nil
+					},
+				},
+			},
+		}),
+	})
+	require.NoError(t, err)
+	return &s
+}
+
+func TestReferences_UseResultsOfPreviousOperations(t *testing.T) {
+	mh := graphqlmultipart.NewHandler(
+		newAlbumSchema(t),
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithBatchConcurrency(4),
+	)
+
+	resp := httptest.NewRecorder()
+	mh.ServeHTTP(resp, newFileUploadRequest(
+		map[string]string{
+			"operations": `[
+				{"query":"mutation { createAlbum(name: \"trip\") { id } }","variables":{}},
+				{
+					"query":"mutation ($album: ID!, $photo: Upload) { addPhoto(albumId: $album, photo: $photo) { albumId, filename } }",
+					"variables":{"album":{"$ref":"0.data.createAlbum.id"},"photo":null}
+				},
+				{
+					"query":"mutation ($album: ID!, $photo: Upload) { addPhoto(albumId: $album, photo: $photo) { albumId, filename } }",
+					"variables":{"album":{"$ref":"1.data.addPhoto.albumId"},"photo":null}
+				},
+				{
+					"query":"mutation ($album: ID!, $photo: Upload) { addPhoto(albumId: $album, photo: $photo) { albumId, filename } }",
+					"variables":{"album":{"$ref":"4.data.createAlbum.id"},"photo":null}
+				},
+				{
+					"query":"mutation ($album: ID!, $photo: Upload) { addPhoto(albumId: $album, photo: $photo) { albumId, filename } }",
+					"variables":{"album":{"$ref":"0.data.missing"},"photo":null}
+				}
+			]`,
+			"map": `{"photo":["1.variables.photo","2.variables.photo","3.variables.photo","4.variables.photo"]}`,
+		},
+		map[string]string{"photo": "handler.go"},
+	))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+
+	require.JSONEq(t, `[
+		{"data":{"createAlbum":{"id":"album-trip"}}},
+		{"data":{"addPhoto":{"albumId":"album-trip","filename":"handler.go"}}},
+		{"data":{"addPhoto":{"albumId":"album-trip","filename":"handler.go"}}},
+		`+getJSONError(graphqlmultipart.InvalidReferenceMessage, "4.data.createAlbum.id")+`,
+		`+getJSONError(graphqlmultipart.InvalidReferenceMessage, "0.data.missing")+`
+	]`, string(body))
+}
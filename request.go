@@ -0,0 +1,161 @@
+package graphqlmultipart
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"strings"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// FilesBeforeOperationsMessage is shown when a file is sent before the
+// operations and map fields, and the handler needs them to read the files
+var FilesBeforeOperationsMessage = fmt.Sprintf("Files must be sent after the \"operations\" and \"map\" fields (%s)", specURL)
+
+// request is the content of the "operations" and "map" fields
+type request struct {
+	ops      []operationField
+	batching bool
+	fileMap  map[string][]mapPath
+}
+
+// requestError is a error of the request as a whole, batching tells if it
+// must be sent as a batch response. errors is used instead of message when
+// the error comes from graphql, so the locations are kept
+type requestError struct {
+	outcome  string
+	message  string
+	errors   []gqlerrors.FormattedError
+	batching bool
+}
+
+func (e requestError) Error() string {
+	if e.message == "" && len(e.errors) > 0 {
+		return e.errors[0].Message
+	}
+	return e.message
+}
+
+// parseRequest reads the "operations" and "map" fields of the form
+func (m MultipartHandler) parseRequest(form *multipart.Form) (*request, error) {
+	var vs []string
+	var ok bool
+
+	if m.htmlForm != nil && !hasFields(form, "operations") {
+		m.normalizeHTMLForm(form)
+	}
+
+	if m.legacyFormats && isLegacyForm(form) {
+		normalizeLegacyForm(form)
+	}
+
+	if vs, ok = form.Value["operations"]; !ok {
+		return nil, requestError{outcome: OutcomeOperationsFieldMissing, message: OperationsFieldMissingMessage}
+	}
+	opsStr := vs[0]
+
+	// errors of the request as a whole are sent in the same shape the results
+	// would be, so batching clients always receive a array
+	batching := strings.HasPrefix(strings.TrimSpace(opsStr), "[")
+
+	if vs, ok = form.Value["map"]; !ok {
+		return nil, requestError{outcome: OutcomeMapFieldMissing, message: MapFieldMissingMessage, batching: batching}
+	}
+	fileMapStr := vs[0]
+
+	fileMap := make(map[string][]string)
+	if err := json.Unmarshal([]byte(fileMapStr), &fileMap); err != nil {
+		return nil, requestError{outcome: OutcomeInvalidMapField, message: InvalidMapFieldMessage, batching: batching}
+	}
+
+	invalid := requestError{outcome: OutcomeInvalidOperationsField, message: InvalidOperationsFieldMessage, batching: batching}
+
+	ops := make([]operationField, 0)
+	if err := json.Unmarshal([]byte(opsStr), &ops); err != nil {
+		op := operationField{}
+		if err := json.Unmarshal([]byte(opsStr), &op); err != nil {
+			return nil, invalid
+		}
+
+		ops = append(ops, op)
+	}
+
+	if len(ops) == 0 {
+		return nil, invalid
+	}
+
+	// batched operations are checked as the single ones, as they are
+	// executed the same way
+	for _, op := range ops {
+		_, _, persisted := persistedQueryHash(op)
+		trusted := op.DocumentID != "" && m.trustedDocuments != nil
+		if (len(op.Query) == 0 && !persisted && !trusted) || op.Variables == nil {
+			return nil, invalid
+		}
+	}
+
+	if batching {
+		if m.disableBatching {
+			return nil, requestError{outcome: OutcomeBatchingDisabled, message: BatchingDisabledMessage, batching: true}
+		}
+
+		if m.maxBatchSize > 0 && len(ops) > m.maxBatchSize {
+			return nil, requestError{
+				outcome:  OutcomeBatchTooLarge,
+				message:  fmt.Sprintf(BatchTooLargeMessage, len(ops), m.maxBatchSize),
+				batching: true,
+			}
+		}
+	}
+
+	for i := range ops {
+		if m.trustedDocuments != nil {
+			if err := m.resolveTrustedDocument(i, &ops[i], batching); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := m.resolvePersistedQuery(&ops[i], batching); err != nil {
+			return nil, err
+		}
+	}
+
+	// the paths are parsed once, so a path that can't be read fails the
+	// request before any operation is executed
+	paths := make(map[string][]mapPath, len(fileMap))
+	for f, ps := range fileMap {
+		paths[f] = make([]mapPath, len(ps))
+		for i, raw := range ps {
+			p, err := parseMapPath(raw)
+			if err != nil {
+				return nil, requestError{
+					outcome:  OutcomeInvalidMapPath,
+					message:  fmt.Sprintf(InvalidMapPathMessage, raw, f),
+					batching: batching,
+				}
+			}
+			paths[f][i] = p
+		}
+	}
+
+	return &request{ops: ops, batching: batching, fileMap: paths}, nil
+}
+
+// operations lists the operations of the request for the hooks, without
+// their files
+func (req *request) operations() []Operation {
+	ops := make([]Operation, len(req.ops))
+	for i, op := range req.ops {
+		t, _ := operationType(op.Query, op.OperationName)
+		ops[i] = Operation{
+			Index:         i,
+			Type:          t,
+			Query:         op.Query,
+			OperationName: op.OperationName,
+			Variables:     *op.Variables,
+			Extensions:    op.Extensions,
+		}
+	}
+	return ops
+}
@@ -0,0 +1,54 @@
+package graphqlmultipart
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SchemaNotFoundMessage is shown when the SchemaResolver finds no schema for
+// the request
+var SchemaNotFoundMessage = "No schema was found for the request"
+
+// SchemaResolver chooses the schema a request will be executed against, like
+// by its host, headers or path. Returning a error rejects the request
+type SchemaResolver func(r *http.Request) (*graphql.Schema, error)
+
+// WithSchemaResolver makes the handler choose the schema of each request with
+// the resolver, instead of always using the one it was created with
+func WithSchemaResolver(resolver SchemaResolver) Option {
+	return func(m *MultipartHandler) {
+		m.schemaResolver = resolver
+	}
+}
+
+// AtomicSchema holds a schema that can be replaced while requests are being
+// handled, its Resolve can be used with WithSchemaResolver
+type AtomicSchema struct {
+	v atomic.Value
+}
+
+// NewAtomicSchema creates a AtomicSchema holding the schema
+func NewAtomicSchema(s *graphql.Schema) *AtomicSchema {
+	a := &AtomicSchema{}
+	a.Store(s)
+	return a
+}
+
+// Load retrieves the current schema
+func (a *AtomicSchema) Load() *graphql.Schema {
+	s, _ := a.v.Load().(*graphql.Schema)
+	return s
+}
+
+// Store replaces the schema, the requests already being handled keep the
+// schema they started with
+func (a *AtomicSchema) Store(s *graphql.Schema) {
+	a.v.Store(s)
+}
+
+// Resolve implements SchemaResolver, returning the current schema
+func (a *AtomicSchema) Resolve(r *http.Request) (*graphql.Schema, error) {
+	return a.Load(), nil
+}
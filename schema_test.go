@@ -0,0 +1,117 @@
+package graphqlmultipart_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaResolver(t *testing.T) {
+	attachment := newAttachmentSchema(t)
+	resolver := func(r *http.Request) (*graphql.Schema, error) {
+		switch r.Header.Get("X-Tenant") {
+		case "uploads":
+			return &testutil.Schema, nil
+		case "attachments":
+			return attachment, nil
+		case "none":
+			return nil, nil
+		}
+		return nil, errors.New("unknown tenant")
+	}
+
+	mh := graphqlmultipart.NewHandler(
+		nil,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithSchemaResolver(resolver),
+		graphqlmultipart.WithEarlyValidation(),
+		graphqlmultipart.WithDocumentCache(10),
+	)
+
+	cases := map[string]string{
+		"uploads": `{"data":{"upload":{"filename":"handler.go","size":` + handlerSize(t) + `}}}`,
+		"attachments": `{"data":null,"errors":[
+			{"message":"Unknown type \"Upload\".","locations":[{"line":1,"column":13}]},
+			{"message":"Cannot query field \"upload\" on type \"Query\".","locations":[{"line":1,"column":23}]}
+		]}`,
+		"none":  getJSONError(graphqlmultipart.SchemaNotFoundMessage),
+		"other": getJSONError("unknown tenant"),
+	}
+
+	for tenant, respo := range cases {
+		t.Run(tenant, func(t *testing.T) {
+			req := newSimpleUploadRequest()
+			req.Header.Set("X-Tenant", tenant)
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, respo, string(body))
+		})
+	}
+}
+
+func TestAtomicSchema_HotSwap(t *testing.T) {
+	schema := graphqlmultipart.NewAtomicSchema(&testutil.Schema)
+	attachment := newAttachmentSchema(t)
+
+	mh := graphqlmultipart.NewHandler(
+		nil,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithSchemaResolver(schema.Resolve),
+		graphqlmultipart.WithBatchConcurrency(2),
+	)
+
+	send := func() string {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newBatchRequest(2))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		return string(body)
+	}
+
+	uploaded := `[{"data":{"upload":{"filename":"handler.go"}}},{"data":{"upload":{"filename":"handler.go"}}}]`
+	require.JSONEq(t, uploaded, send())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				schema.Store(attachment)
+			} else {
+				schema.Store(&testutil.Schema)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			send()
+		}()
+	}
+	wg.Wait()
+
+	schema.Store(attachment)
+	require.Equal(t, attachment, schema.Load())
+	require.JSONEq(t, `[
+		`+getJSONError(graphqlmultipart.InvalidMapPathMessage, "0.variables.file", "file")+`,
+		`+getJSONError(graphqlmultipart.InvalidMapPathMessage, "1.variables.file", "file")+`
+	]`, send())
+
+	schema.Store(&testutil.Schema)
+	require.JSONEq(t, uploaded, send())
+}
@@ -0,0 +1,95 @@
+package graphqlmultipart
+
+import (
+	"fmt"
+	"mime/multipart"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// InlineUploadMessage is shown when a Upload variable position has a value
+// that was not sent as a file through the map
+var InlineUploadMessage = fmt.Sprintf("Variable \"$%%[1]s\" got invalid value at \"%%[2]s\", Upload values must be sent as files through the \"map\" field (%s)", specURL)
+
+// WithStrictUploads makes the handler reject the operations that have values
+// other than files in positions typed as Upload, instead of letting them be
+// read as null. Null is still accepted, as it is how a optional file is left
+// out
+func WithStrictUploads() Option {
+	return func(m *MultipartHandler) {
+		m.strictUploads = true
+	}
+}
+
+// checkInlineUploads checks that every value of the variables in a position
+// typed as Upload is a file, or null
+func (m MultipartHandler) checkInlineUploads(def *ast.OperationDefinition, vars map[string]interface{}) error {
+	for _, vd := range def.VariableDefinitions {
+		name := vd.Variable.Name.Value
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+
+		t := typeFromAST(*m.Schema, vd.Type)
+		if t == nil {
+			continue
+		}
+
+		if p, ok := findInlineUpload(t, v, name); !ok {
+			return fmt.Errorf(InlineUploadMessage, name, p)
+		}
+	}
+
+	return nil
+}
+
+// findInlineUpload walks the value following its type, and returns the path of
+// the first Upload position that is not a file
+func findInlineUpload(t graphql.Type, v interface{}, path string) (string, bool) {
+	if v == nil {
+		return "", true
+	}
+
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return findInlineUpload(t.OfType, v, path)
+	case *graphql.List:
+		vs, ok := v.([]interface{})
+		if !ok {
+			// a single value is accepted where a list is expected
+			return findInlineUpload(t.OfType, v, path)
+		}
+
+		for i, e := range vs {
+			if p, ok := findInlineUpload(t.OfType, e, path+"."+strconv.Itoa(i)); !ok {
+				return p, false
+			}
+		}
+	case *graphql.InputObject:
+		fs, ok := v.(map[string]interface{})
+		if !ok {
+			return "", true
+		}
+
+		for n, f := range t.Fields() {
+			if p, ok := findInlineUpload(f.Type, fs[n], path+"."+n); !ok {
+				return p, false
+			}
+		}
+	case *graphql.Scalar:
+		if t != Upload {
+			return "", true
+		}
+
+		switch v.(type) {
+		case *multipart.FileHeader, multipart.FileHeader:
+			return "", true
+		}
+		return path, false
+	}
+
+	return "", true
+}
@@ -0,0 +1,61 @@
+package graphqlmultipart_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictUploads(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	cases := map[string]test{
+		"inline_value": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":"handler.go"}}`,
+					"map":        `{}`,
+				},
+				map[string]string{},
+			),
+			respo: getJSONError(graphqlmultipart.InlineUploadMessage, "file", "file"),
+		},
+		"inline_inside_input": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($input:SpecialUploadInput) { specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null,{"path":"/etc/passwd"}]}}}`,
+					"map":        `{"file":["variables.input.files.0"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InlineUploadMessage, "input", "input.files.1"),
+		},
+		"mapped_files": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($input:SpecialUploadInput) { specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null,null]}}}`,
+					"map":        `{"file":["variables.input.files.0","variables.input.files.1"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `{"data":{"specialUploads":[{"filename":"handler.go"},{"filename":"handler.go"}]}}`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler(graphqlmultipart.WithStrictUploads()).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
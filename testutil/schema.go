@@ -63,107 +63,113 @@ type uploadedFile struct {
 }
 
 func init() {
-	var err error
-	Schema, err = graphql.NewSchema(graphql.SchemaConfig{
-		Query: graphql.NewObject(graphql.ObjectConfig{
-			Name: "RootQuery",
-			Fields: graphql.Fields{
-				"upload": &graphql.Field{
-					Name:        "UploadQuery",
-					Description: "Receives a Uploaded file and returns its metadata",
-					Args: graphql.FieldConfigArgument{
-						"file": &graphql.ArgumentConfig{
-							Type: graphqlmultipart.Upload,
-						},
-					},
-					Type: uploadedType,
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-						file := p.Args["file"].(*multipart.FileHeader)
-						r := uploadedFile{
-							Filename: file.Filename,
-							Size:     file.Size,
-							Headers:  make([]uploadedHeader, len(file.Header)),
-						}
+	uploadFields := graphql.Fields{
+		"upload": &graphql.Field{
+			Name:        "UploadQuery",
+			Description: "Receives a Uploaded file and returns its metadata",
+			Args: graphql.FieldConfigArgument{
+				"file": &graphql.ArgumentConfig{
+					Type: graphqlmultipart.Upload,
+				},
+			},
+			Type: uploadedType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				file := p.Args["file"].(*multipart.FileHeader)
+				r := uploadedFile{
+					Filename: file.Filename,
+					Size:     file.Size,
+					Headers:  make([]uploadedHeader, len(file.Header)),
+				}
 
-						i := 0
-						for n, vs := range file.Header {
-							r.Headers[i] = uploadedHeader{
-								Name:   n,
-								Values: vs,
-							}
-							i++
-						}
-						return r, nil
-					},
+				i := 0
+				for n, vs := range file.Header {
+					r.Headers[i] = uploadedHeader{
+						Name:   n,
+						Values: vs,
+					}
+					i++
+				}
+				return r, nil
+			},
+		},
+		"uploads": &graphql.Field{
+			Name:        "UploadsQuery",
+			Description: "Receives a Uploaded file and returns its metadata",
+			Args: graphql.FieldConfigArgument{
+				"files": &graphql.ArgumentConfig{
+					Type: graphql.NewList(graphqlmultipart.Upload),
 				},
-				"uploads": &graphql.Field{
-					Name:        "UploadsQuery",
-					Description: "Receives a Uploaded file and returns its metadata",
-					Args: graphql.FieldConfigArgument{
-						"files": &graphql.ArgumentConfig{
-							Type: graphql.NewList(graphqlmultipart.Upload),
-						},
-					},
-					Type: graphql.NewList(uploadedType),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-						files := p.Args["files"].([]interface{})
-						rs := make([]uploadedFile, len(files))
-						for i, f := range files {
-							file := f.(*multipart.FileHeader)
-							r := uploadedFile{
-								Filename: file.Filename,
-								Size:     file.Size,
-								Headers:  make([]uploadedHeader, len(file.Header)),
-							}
+			},
+			Type: graphql.NewList(uploadedType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				files := p.Args["files"].([]interface{})
+				rs := make([]uploadedFile, len(files))
+				for i, f := range files {
+					file := f.(*multipart.FileHeader)
+					r := uploadedFile{
+						Filename: file.Filename,
+						Size:     file.Size,
+						Headers:  make([]uploadedHeader, len(file.Header)),
+					}
 
-							j := 0
-							for n, vs := range file.Header {
-								r.Headers[j] = uploadedHeader{
-									Name:   n,
-									Values: vs,
-								}
-								j++
-							}
-							rs[i] = r
+					j := 0
+					for n, vs := range file.Header {
+						r.Headers[j] = uploadedHeader{
+							Name:   n,
+							Values: vs,
 						}
-						return rs, nil
-					},
-				}, "specialUploads": &graphql.Field{
-					Name:        "SpecialUploadsQuery",
-					Description: "Receives a Uploaded file and returns its metadata",
-					Args: graphql.FieldConfigArgument{
-						"input": &graphql.ArgumentConfig{
-							Type: specialUploadInput,
-						},
-					},
-					Type: graphql.NewList(uploadedType),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-						input := p.Args["input"].(map[string]interface{})
+						j++
+					}
+					rs[i] = r
+				}
+				return rs, nil
+			},
+		}, "specialUploads": &graphql.Field{
+			Name:        "SpecialUploadsQuery",
+			Description: "Receives a Uploaded file and returns its metadata",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: specialUploadInput,
+				},
+			},
+			Type: graphql.NewList(uploadedType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				input := p.Args["input"].(map[string]interface{})
 
-						files := input["files"].([]interface{})
-						rs := make([]uploadedFile, len(files))
-						for i, f := range files {
-							file := f.(*multipart.FileHeader)
-							r := uploadedFile{
-								Filename: file.Filename,
-								Size:     file.Size,
-								Headers:  make([]uploadedHeader, len(file.Header)),
-							}
+				files := input["files"].([]interface{})
+				rs := make([]uploadedFile, len(files))
+				for i, f := range files {
+					file := f.(*multipart.FileHeader)
+					r := uploadedFile{
+						Filename: file.Filename,
+						Size:     file.Size,
+						Headers:  make([]uploadedHeader, len(file.Header)),
+					}
 
-							j := 0
-							for n, vs := range file.Header {
-								r.Headers[j] = uploadedHeader{
-									Name:   n,
-									Values: vs,
-								}
-								j++
-							}
-							rs[i] = r
+					j := 0
+					for n, vs := range file.Header {
+						r.Headers[j] = uploadedHeader{
+							Name:   n,
+							Values: vs,
 						}
-						return rs, nil
-					},
-				},
+						j++
+					}
+					rs[i] = r
+				}
+				return rs, nil
 			},
+		},
+	}
+
+	var err error
+	Schema, err = graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "RootQuery",
+			Fields: uploadFields,
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "RootMutation",
+			Fields: uploadFields,
 		}),
 	})
 	if err != nil {
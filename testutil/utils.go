@@ -8,11 +8,24 @@ import (
 	"os"
 )
 
-// NewGraphQLFileUploadRequest creates a simple send operations and map through the `fields` param
+// NewGraphQLFileUploadRequest creates a simple send operations and map through the `fields` param,
+// the fields are sent before the files, "operations" and "map" first, as the spec requires
 func NewGraphQLFileUploadRequest(url string, fields map[string]string, files map[string]string) *http.Request {
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
 
+	for _, key := range []string{"operations", "map"} {
+		if val, ok := fields[key]; ok {
+			_ = writer.WriteField(key, val)
+		}
+	}
+
+	for key, val := range fields {
+		if key != "operations" && key != "map" {
+			_ = writer.WriteField(key, val)
+		}
+	}
+
 	for paramName, path := range files {
 		file, err := os.Open(path)
 		if err != nil {
@@ -35,10 +48,6 @@ func NewGraphQLFileUploadRequest(url string, fields map[string]string, files map
 		part.Write(fileContents)
 	}
 
-	for key, val := range fields {
-		_ = writer.WriteField(key, val)
-	}
-
 	if err := writer.Close(); err != nil {
 		panic(err)
 	}
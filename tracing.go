@@ -0,0 +1,50 @@
+package graphqlmultipart
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lucassabreu/graphql-multipart-middleware"
+
+var (
+	attrErrorClass    = attribute.Key("graphqlmultipart.error_class")
+	attrFileCount     = attribute.Key("graphqlmultipart.file_count")
+	attrFieldName     = attribute.Key("graphqlmultipart.field_name")
+	attrFileName      = attribute.Key("graphqlmultipart.file_name")
+	attrFileSize      = attribute.Key("graphqlmultipart.file_size")
+	attrBytes         = attribute.Key("graphqlmultipart.bytes")
+	attrBatchSize     = attribute.Key("graphqlmultipart.batch_size")
+	attrOperationName = attribute.Key("graphql.operation.name")
+)
+
+// WithTracerProvider sets the provider used to create the spans of the
+// handler, by default the global one from otel.GetTracerProvider is used
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(m *MultipartHandler) {
+		m.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithPropagator sets the propagator used to extract the trace context from
+// the incoming requests, by default the global one from
+// otel.GetTextMapPropagator is used
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(m *MultipartHandler) {
+		m.propagator = p
+	}
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(instrumentationName)
+}
+
+// recordError marks the span as failed with the error class informed
+func recordError(span trace.Span, class string, err error) {
+	span.SetAttributes(attrErrorClass.String(class))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
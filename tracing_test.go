@@ -0,0 +1,110 @@
+package graphqlmultipart_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracedHandler() (http.Handler, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	return graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithTracerProvider(tp),
+		graphqlmultipart.WithPropagator(propagation.TraceContext{}),
+	), exp
+}
+
+func spanAttr(s tracetest.SpanStub, k string) attribute.Value {
+	for _, a := range s.Attributes {
+		if string(a.Key) == k {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_CreatesSpansForEachStage(t *testing.T) {
+	mh, exp := newTracedHandler()
+
+	r := newFileUploadRequest(
+		map[string]string{
+			"operations": `[
+				{"query":"query A($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"A"},
+				{"query":"query B($file: Upload){ upload(file:$file){filename} }","variables":{"file":null},"operationName":"B"}
+			]`,
+			"map": `{"file":["0.variables.file","1.variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	mh.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := exp.GetSpans()
+	names := make(map[string][]tracetest.SpanStub)
+	for _, s := range spans {
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext.TraceID().String())
+		names[s.Name] = append(names[s.Name], s)
+	}
+
+	require.Len(t, names["graphqlmultipart.request"], 1)
+	require.Len(t, names["graphqlmultipart.parse"], 1)
+	require.Len(t, names["graphqlmultipart.file"], 1)
+	require.Len(t, names["graphqlmultipart.inject"], 2)
+	require.Len(t, names["graphqlmultipart.execute"], 2)
+
+	req := names["graphqlmultipart.request"][0]
+	require.Equal(t, "00f067aa0ba902b7", req.Parent.SpanID().String())
+	require.Equal(t, int64(1), spanAttr(req, "graphqlmultipart.file_count").AsInt64())
+	require.Equal(t, int64(2), spanAttr(req, "graphqlmultipart.batch_size").AsInt64())
+	require.NotZero(t, spanAttr(req, "graphqlmultipart.bytes").AsInt64())
+
+	parse := names["graphqlmultipart.parse"][0]
+	file := names["graphqlmultipart.file"][0]
+	require.Equal(t, parse.SpanContext.SpanID(), file.Parent.SpanID())
+	require.Equal(t, "handler.go", spanAttr(file, "graphqlmultipart.file_name").AsString())
+
+	ops := []string{}
+	for _, s := range names["graphqlmultipart.execute"] {
+		require.Equal(t, req.SpanContext.SpanID(), s.Parent.SpanID())
+		ops = append(ops, spanAttr(s, "graphql.operation.name").AsString())
+	}
+	require.ElementsMatch(t, []string{"A", "B"}, ops)
+}
+
+func TestTracing_RecordsErrorClass(t *testing.T) {
+	mh, exp := newTracedHandler()
+
+	mh.ServeHTTP(httptest.NewRecorder(), newFileUploadRequest(
+		map[string]string{
+			"operations": `{"query":"query hero{id}","variables":{}}`,
+			"map":        `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	))
+
+	for _, s := range exp.GetSpans() {
+		switch s.Name {
+		case "graphqlmultipart.request", "graphqlmultipart.inject":
+			require.Equal(t, codes.Error, s.Status.Code, s.Name)
+			require.Equal(t, graphqlmultipart.OutcomeInvalidMapPath, spanAttr(s, "graphqlmultipart.error_class").AsString())
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package graphqlmultipart
+
+import (
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/textproto"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Transformer changes the content of a file part while it is received, the
+// header can be changed and will be the one seen in the *multipart.FileHeader
+// after the file was fully read. Returning a error rejects the request
+type Transformer func(header textproto.MIMEHeader, body io.Reader) (io.Reader, error)
+
+// WithTransformers sets a chain of Transformer that every file part will pass
+// through in the order informed, the content of the *multipart.FileHeader
+// will be the output of the last one
+func WithTransformers(ts ...Transformer) Option {
+	return func(m *MultipartHandler) {
+		m.transformers = append(m.transformers, ts...)
+	}
+}
+
+var (
+	// SHA256Transformer sets the hex encoded SHA-256 of the file content on
+	// the "X-Content-Sha256" header
+	SHA256Transformer = NewHashTransformer("X-Content-Sha256", sha256.New)
+
+	// MD5Transformer sets the hex encoded MD5 of the file content on the
+	// "X-Content-Md5" header
+	MD5Transformer = NewHashTransformer("X-Content-Md5", md5.New)
+)
+
+// NewHashTransformer creates a Transformer that calculates the hash of the
+// file content while it is read, setting it hex encoded on the header key
+func NewHashTransformer(key string, newHash func() hash.Hash) Transformer {
+	return func(header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+		return &hashReader{
+			r:      body,
+			h:      newHash(),
+			key:    key,
+			header: header,
+		}, nil
+	}
+}
+
+type hashReader struct {
+	r      io.Reader
+	h      hash.Hash
+	key    string
+	header textproto.MIMEHeader
+}
+
+func (h *hashReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.h.Write(p[:n])
+	if err == io.EOF {
+		h.header.Set(h.key, hex.EncodeToString(h.h.Sum(nil)))
+	}
+	return n, err
+}
+
+// DefaultMaxDecompressedSize is the limit of bytes a file can have after being
+// decompressed by the DecompressTransformer
+const DefaultMaxDecompressedSize = int64(32 << 20)
+
+// DecompressedTooLargeMessage is shown when a file is larger than the limit
+// of the decompress Transformer after being decompressed
+var DecompressedTooLargeMessage = "File content is larger than %[1]d bytes after being decompressed"
+
+// DecompressTransformer decompresses the file parts sent with a
+// "Content-Encoding" of "gzip" or "zstd", removing the header after it. The
+// files can have up to DefaultMaxDecompressedSize bytes after decompressed
+var DecompressTransformer = NewDecompressTransformer(DefaultMaxDecompressedSize)
+
+// NewDecompressTransformer creates a Transformer like the DecompressTransformer
+// that fails the file parts with more than limit bytes after decompressed, as
+// a small compressed part can fill the memory or disk. A limit of zero or less
+// uses the DefaultMaxDecompressedSize
+func NewDecompressTransformer(limit int64) Transformer {
+	if limit <= 0 {
+		limit = DefaultMaxDecompressedSize
+	}
+
+	return func(header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+		enc := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))
+		switch enc {
+		case "", "identity":
+			return body, nil
+		case "gzip", "x-gzip":
+			r, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("file content is not valid gzip: %s", err.Error())
+			}
+			header.Del("Content-Encoding")
+			return &maxSizeReader{r: r, limit: limit}, nil
+		case "zstd":
+			d, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, fmt.Errorf("file content is not valid zstd: %s", err.Error())
+			}
+			header.Del("Content-Encoding")
+			return &maxSizeReader{r: d.IOReadCloser(), limit: limit}, nil
+		default:
+			return nil, fmt.Errorf("content encoding \"%s\" is not supported", enc)
+		}
+	}
+}
+
+// maxSizeReader fails the file once more than limit bytes are read from it
+type maxSizeReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.read += int64(n)
+	if m.read > m.limit {
+		return 0, rejectedError{err: fmt.Errorf(DecompressedTooLargeMessage, m.limit)}
+	}
+	return n, err
+}
+
+func (m *maxSizeReader) Close() error {
+	if c, ok := m.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// encryptedChunkSize is the size of plain text sealed in each chunk by the
+// encryption Transformer
+const encryptedChunkSize = 64 * 1024
+
+// NewEncryptTransformer creates a Transformer that encrypts the file content
+// with AES-GCM using the key informed (16, 24 or 32 bytes long), so it is
+// stored encrypted. The content must be read using NewDecryptReader
+func NewEncryptTransformer(key []byte) (Transformer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+		prefix := make([]byte, aead.NonceSize()-4)
+		if _, err := rand.Read(prefix); err != nil {
+			return nil, err
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(encrypt(aead, prefix, body, pw))
+		}()
+		return pr, nil
+	}, nil
+}
+
+// NewDecryptReader reads the content of a file encrypted by the Transformer
+// created with NewEncryptTransformer using the same key, it must be closed if
+// not read until the end
+func NewDecryptReader(key []byte, r io.Reader) (io.ReadCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(decrypt(aead, r, pw))
+	}()
+	return pr, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
+
+// the encrypted content is written as the nonce prefix, followed by chunks of
+// a uint32 length and the sealed text, the nonce of each chunk is the prefix
+// plus the chunk index and the last chunk is marked in its additional data
+// so truncated contents are detected
+func encrypt(aead cipher.AEAD, prefix []byte, r io.Reader, w io.Writer) error {
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+
+	buf := make([]byte, encryptedChunkSize)
+	next := make([]byte, encryptedChunkSize)
+	n, err := io.ReadFull(r, buf)
+	for i := uint32(0); ; i++ {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		last := err != nil
+		var m int
+		if !last {
+			m, err = io.ReadFull(r, next)
+			last = err == io.EOF
+		}
+
+		binary.BigEndian.PutUint32(nonce[len(prefix):], i)
+		sealed := aead.Seal(nil, nonce, buf[:n], chunkAD(last))
+
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(sealed)))
+		if _, err := w.Write(size); err != nil {
+			return err
+		}
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+
+		buf, next = next, buf
+		n = m
+	}
+}
+
+func decrypt(aead cipher.AEAD, r io.Reader, w io.Writer) error {
+	nonce := make([]byte, aead.NonceSize())
+	prefix := len(nonce) - 4
+	if _, err := io.ReadFull(r, nonce[:prefix]); err != nil {
+		return err
+	}
+
+	size := make([]byte, 4)
+	for i := uint32(0); ; i++ {
+		if _, err := io.ReadFull(r, size); err != nil {
+			if err == io.EOF {
+				return errors.New("encrypted content is truncated")
+			}
+			return err
+		}
+
+		l := binary.BigEndian.Uint32(size)
+		if l > uint32(encryptedChunkSize+aead.Overhead()) {
+			return errors.New("encrypted content is not valid")
+		}
+
+		sealed := make([]byte, l)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return err
+		}
+
+		binary.BigEndian.PutUint32(nonce[prefix:], i)
+		plain, err := aead.Open(nil, nonce, sealed, chunkAD(false))
+		last := false
+		if err != nil {
+			plain, err = aead.Open(nil, nonce, sealed, chunkAD(true))
+			last = true
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
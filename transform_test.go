@@ -0,0 +1,208 @@
+package graphqlmultipart_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newEncodedUploadRequest(content []byte, encoding string) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	w.WriteField("operations", `{
+		"query":"query($file:Upload) { upload(file: $file){ size, headers { name, values } } }",
+		"variables":{"file":null}
+	}`)
+	w.WriteField("map", `{"file":["variables.file"]}`)
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="file.txt"`)
+	h.Set("Content-Type", "text/plain")
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	p, _ := w.CreatePart(h)
+	p.Write(content)
+	w.Close()
+
+	r, _ := http.NewRequest("POST", "/graphql", body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestTransformers_DecompressAndHash(t *testing.T) {
+	content := bytes.Repeat([]byte("graphql multipart "), 1000)
+	sum := sha256.Sum256(content)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(content)
+	zw.Close()
+
+	mh := graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithTransformers(
+			graphqlmultipart.DecompressTransformer,
+			graphqlmultipart.SHA256Transformer,
+		),
+	)
+
+	cases := map[string]*http.Request{
+		"gzip":  newEncodedUploadRequest(gz.Bytes(), "gzip"),
+		"plain": newEncodedUploadRequest(content, ""),
+	}
+
+	for name, r := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, r)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+
+			var res struct {
+				Data struct {
+					Upload struct {
+						Size    int
+						Headers []struct {
+							Name   string
+							Values []string
+						}
+					}
+				}
+			}
+			require.NoError(t, json.Unmarshal(body, &res), string(body))
+			require.Equal(t, len(content), res.Data.Upload.Size)
+
+			headers := make(map[string][]string)
+			for _, h := range res.Data.Upload.Headers {
+				headers[h.Name] = h.Values
+			}
+			require.Equal(t, map[string][]string{
+				"Content-Disposition": {`form-data; name="file"; filename="file.txt"`},
+				"Content-Type":        {"text/plain"},
+				"X-Content-Sha256":    {hex.EncodeToString(sum[:])},
+			}, headers)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newEncodedUploadRequest(content, "br"))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.JSONEq(t, getJSONError(`content encoding "br" is not supported`), string(body))
+	})
+}
+
+func TestTransformers_DecompressLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("graphql multipart "), 1000)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(content)
+	gw.Close()
+
+	var zs bytes.Buffer
+	zw, _ := zstd.NewWriter(&zs)
+	zw.Write(content)
+	zw.Close()
+
+	cases := map[string]struct {
+		limit int64
+		req   *http.Request
+		respo string
+	}{
+		"gzip": {
+			limit: 1000,
+			req:   newEncodedUploadRequest(gz.Bytes(), "gzip"),
+			respo: getJSONError(graphqlmultipart.DecompressedTooLargeMessage, 1000),
+		},
+		"zstd": {
+			limit: 1000,
+			req:   newEncodedUploadRequest(zs.Bytes(), "zstd"),
+			respo: getJSONError(graphqlmultipart.DecompressedTooLargeMessage, 1000),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			mh := graphqlmultipart.NewHandler(
+				&testutil.Schema,
+				1*1024,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("should not have forwarded the request"))
+				}),
+				graphqlmultipart.WithTransformers(graphqlmultipart.NewDecompressTransformer(test.limit)),
+			)
+
+			resp := httptest.NewRecorder()
+			mh.ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+
+	t.Run("inside_limit", func(t *testing.T) {
+		mh := graphqlmultipart.NewHandler(
+			&testutil.Schema,
+			1*1024,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("should not have forwarded the request"))
+			}),
+			graphqlmultipart.WithTransformers(graphqlmultipart.NewDecompressTransformer(int64(len(content)))),
+		)
+
+		resp := httptest.NewRecorder()
+		mh.ServeHTTP(resp, newEncodedUploadRequest(gz.Bytes(), "gzip"))
+		body, _ := ioutil.ReadAll(resp.Result().Body)
+		require.Contains(t, string(body), `"size":`+strconv.Itoa(len(content)))
+	})
+}
+
+func TestTransformers_EncryptAndDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	encrypt, err := graphqlmultipart.NewEncryptTransformer(key)
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 10, 64 * 1024, 150 * 1024} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			content := bytes.Repeat([]byte{'a'}, size)
+
+			r, err := encrypt(make(textproto.MIMEHeader), bytes.NewReader(content))
+			require.NoError(t, err)
+			encrypted, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.NotContains(t, string(encrypted), "aaaa")
+
+			d, err := graphqlmultipart.NewDecryptReader(key, bytes.NewReader(encrypted))
+			require.NoError(t, err)
+			decrypted, err := ioutil.ReadAll(d)
+			require.NoError(t, err)
+			require.Equal(t, content, decrypted)
+
+			d, err = graphqlmultipart.NewDecryptReader(key, io.LimitReader(bytes.NewReader(encrypted), int64(len(encrypted)-1)))
+			require.NoError(t, err)
+			_, err = ioutil.ReadAll(d)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package graphqlmultipart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// UntrustedDocumentMessage is shown when a operation is not one of the
+// TrustedDocuments of the handler
+var UntrustedDocumentMessage = "Operation %[1]d is not a trusted document"
+
+// TrustedDocuments is a registry of pre-approved operation documents, looked
+// up by their id or by the SHA-256 hash of their body
+type TrustedDocuments struct {
+	documents map[string]string
+}
+
+// manifest is the format of the persisted query manifests generated by the
+// Apollo tools
+type manifest struct {
+	Operations []struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// NewTrustedDocuments creates a registry with the documents, by their id
+func NewTrustedDocuments(documents map[string]string) *TrustedDocuments {
+	d := &TrustedDocuments{documents: make(map[string]string, len(documents)*2)}
+	for id, body := range documents {
+		d.documents[id] = body
+
+		sum := sha256.Sum256([]byte(body))
+		d.documents[hex.EncodeToString(sum[:])] = body
+	}
+	return d
+}
+
+// LoadTrustedDocuments reads the documents from a JSON manifest file, it can
+// be a Apollo persisted query manifest ({"operations":[{"id":..,"body":..}]})
+// or a object with the bodies by their ids
+func LoadTrustedDocuments(filename string) (*TrustedDocuments, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var mf manifest
+	if err := json.Unmarshal(b, &mf); err == nil && mf.Operations != nil {
+		documents := make(map[string]string, len(mf.Operations))
+		for _, op := range mf.Operations {
+			documents[op.ID] = op.Body
+		}
+		return NewTrustedDocuments(documents), nil
+	}
+
+	documents := make(map[string]string)
+	if err := json.Unmarshal(b, &documents); err != nil {
+		return nil, fmt.Errorf("graphqlmultipart: invalid trusted documents manifest %s: %w", filename, err)
+	}
+	return NewTrustedDocuments(documents), nil
+}
+
+// Lookup finds the body of a document by its id or hash, the hash can be
+// prefixed by "sha256:"
+func (d *TrustedDocuments) Lookup(key string) (string, bool) {
+	body, ok := d.documents[strings.TrimPrefix(key, "sha256:")]
+	return body, ok
+}
+
+// WithTrustedDocuments makes the handler execute only the operations in the
+// registry, they can be sent with the "documentId" field, with the
+// "persistedQuery" extension or with the full query. Other operations reject
+// the request before any file is read, so the files must be sent after the
+// "operations" and "map" fields, as the spec says
+func WithTrustedDocuments(d *TrustedDocuments) Option {
+	return func(m *MultipartHandler) {
+		m.trustedDocuments = d
+	}
+}
+
+// resolveTrustedDocument fills the query of the operation from the registry,
+// failing if it is not there
+func (m MultipartHandler) resolveTrustedDocument(i int, op *operationField, batching bool) error {
+	untrusted := requestError{
+		outcome:  OutcomeUntrustedDocument,
+		message:  fmt.Sprintf(UntrustedDocumentMessage, i),
+		batching: batching,
+	}
+
+	key := op.DocumentID
+	if hash, _, ok := persistedQueryHash(*op); ok && key == "" {
+		key = hash
+	}
+
+	if op.Query != "" {
+		sum := sha256.Sum256([]byte(op.Query))
+		key = hex.EncodeToString(sum[:])
+	}
+
+	body, ok := m.trustedDocuments.Lookup(key)
+	if key == "" || !ok {
+		return untrusted
+	}
+
+	op.Query = body
+	return nil
+}
@@ -0,0 +1,143 @@
+package graphqlmultipart_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strconv"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+const trustedQuery = "mutation Upload($file:Upload) { upload(file: $file){ filename } }"
+
+func writeManifest(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "manifest-*.json")
+	require.NoError(t, err)
+	defer f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func newTrustedRequest(operation string) *http.Request {
+	return newFileUploadRequest(
+		map[string]string{
+			"operations": operation,
+			"map":        `{"file":["variables.file"]}`,
+		},
+		map[string]string{"file": "handler.go"},
+	)
+}
+
+func TestTrustedDocuments(t *testing.T) {
+	sum := sha256.Sum256([]byte(trustedQuery))
+	hash := hex.EncodeToString(sum[:])
+
+	docs, err := graphqlmultipart.LoadTrustedDocuments(writeManifest(t, `{
+		"format":"apollo-persisted-query-manifest",
+		"version":1,
+		"operations":[{"id":"upload-1","name":"Upload","type":"mutation","body":`+strconv.Quote(trustedQuery)+`}]
+	}`))
+	require.NoError(t, err)
+
+	type test struct {
+		req      *http.Request
+		respo    string
+		rejected bool
+	}
+
+	cases := map[string]test{
+		"document_id": test{
+			req:   newTrustedRequest(`{"documentId":"upload-1","variables":{"file":null}}`),
+			respo: `{"data":{"upload":{"filename":"handler.go"}}}`,
+		},
+		"document_id_hash": test{
+			req:   newTrustedRequest(`{"documentId":"sha256:` + hash + `","variables":{"file":null}}`),
+			respo: `{"data":{"upload":{"filename":"handler.go"}}}`,
+		},
+		"persisted_query": test{
+			req:   newTrustedRequest(`{"variables":{"file":null},"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}}`),
+			respo: `{"data":{"upload":{"filename":"handler.go"}}}`,
+		},
+		"full_query": test{
+			req:   newTrustedRequest(`{"query":` + strconv.Quote(trustedQuery) + `,"variables":{"file":null}}`),
+			respo: `{"data":{"upload":{"filename":"handler.go"}}}`,
+		},
+		"untrusted_query": test{
+			req:      newTrustedRequest(`{"query":"query($file:Upload) { upload(file: $file){ filename, size } }","variables":{"file":null}}`),
+			respo:    getJSONError(graphqlmultipart.UntrustedDocumentMessage, 0),
+			rejected: true,
+		},
+		"unknown_id": test{
+			req:      newTrustedRequest(`{"documentId":"upload-2","variables":{"file":null}}`),
+			respo:    getJSONError(graphqlmultipart.UntrustedDocumentMessage, 0),
+			rejected: true,
+		},
+		"batching": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"documentId":"upload-1","variables":{"file":null}},
+						{"documentId":"upload-2","variables":{"file":null}}
+					]`,
+					"map": `{"file":["0.variables.file","1.variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo:    "[" + getJSONError(graphqlmultipart.UntrustedDocumentMessage, 1) + "]",
+			rejected: true,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			hooks := graphqlmultipart.Hooks{}
+			if test.rejected {
+				hooks.OnFile = func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+					t.Fatal("files should not be read")
+					return body, nil
+				}
+			}
+
+			resp := httptest.NewRecorder()
+			newPolicyHandler(graphqlmultipart.WithTrustedDocuments(docs), graphqlmultipart.WithHooks(hooks)).
+				ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestTrustedDocuments_NotConfigured(t *testing.T) {
+	resp := httptest.NewRecorder()
+	newPolicyHandler().ServeHTTP(resp, newTrustedRequest(`{"documentId":"upload-1","variables":{"file":null}}`))
+	body, _ := ioutil.ReadAll(resp.Result().Body)
+	require.JSONEq(t, getJSONError(graphqlmultipart.InvalidOperationsFieldMessage), string(body))
+}
+
+func TestLoadTrustedDocuments(t *testing.T) {
+	docs, err := graphqlmultipart.LoadTrustedDocuments(writeManifest(t, `{"upload-1":`+strconv.Quote(trustedQuery)+`}`))
+	require.NoError(t, err)
+
+	body, ok := docs.Lookup("upload-1")
+	require.True(t, ok)
+	require.Equal(t, trustedQuery, body)
+
+	_, err = graphqlmultipart.LoadTrustedDocuments(writeManifest(t, `[1, 2]`))
+	require.Error(t, err)
+
+	_, err = graphqlmultipart.LoadTrustedDocuments("missing.json")
+	require.Error(t, err)
+}
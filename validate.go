@@ -0,0 +1,222 @@
+package graphqlmultipart
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// UploadTypeMismatchMessage is shown when a map path points to a value that
+// is not typed as Upload by the variables of the operation
+var UploadTypeMismatchMessage = fmt.Sprintf("Mapping path \"%%[1]s\" for file %%[2]s points to a value of type %%[3]s, but the expected type is Upload (%s)", specURL)
+
+// WithEarlyValidation makes the handler validate the operations against the
+// Schema, and check that the map paths point to variables that accept a
+// Upload, as soon as the "operations" and "map" fields are read and before any
+// file. The files must be sent after those fields, as the spec says.
+//
+// The validated documents are executed with graphql.Execute instead of
+// graphql.Do, as WithDocumentCache does, so they are not parsed and validated
+// again
+func WithEarlyValidation() Option {
+	return func(m *MultipartHandler) {
+		m.earlyValidation = true
+	}
+}
+
+// validateRequest validates the operations and map paths of the request,
+// keeping the documents of the operations for their execution
+func (m MultipartHandler) validateRequest(req *request) error {
+	defs := make([]*ast.OperationDefinition, len(req.ops))
+	for i, op := range req.ops {
+		doc, errs := m.document(op.Query)
+		if len(errs) > 0 {
+			return requestError{
+				outcome:  OutcomeInvalidQuery,
+				errors:   errs,
+				batching: req.batching,
+			}
+		}
+
+		defs[i] = findOperation(doc, op.OperationName)
+		req.ops[i].doc, req.ops[i].def = doc, defs[i]
+	}
+
+	for f, ps := range req.fileMap {
+		for _, p := range ps {
+			if err := m.validateMapPath(req, defs, f, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateMapPath checks that a path of the map points to a value typed as
+// Upload, or into the extensions of a operation
+func (m MultipartHandler) validateMapPath(req *request, defs []*ast.OperationDefinition, f string, p mapPath) error {
+	invalid := requestError{
+		outcome:  OutcomeInvalidMapPath,
+		message:  fmt.Sprintf(InvalidMapPathMessage, p, f),
+		batching: req.batching,
+	}
+
+	i := 0
+	var prefix []string
+	if req.batching {
+		n, ok := index(p.segments[0])
+		if !ok || n >= len(req.ops) {
+			return invalid
+		}
+		i, prefix = n, p.segments[:1]
+	}
+
+	path, ok := p.relative(prefix...)
+	if !ok || len(path) < 2 || !mapRoots[path[0]] {
+		return invalid
+	}
+
+	// extensions have no types to check them against
+	if path[0] == "extensions" {
+		return nil
+	}
+
+	if defs[i] == nil {
+		return invalid
+	}
+
+	if outcome, err := m.checkUploadPath(defs[i], f, p, path[1:]); err != nil {
+		return requestError{outcome: outcome, message: err.Error(), batching: req.batching}
+	}
+	return nil
+}
+
+// checkUploadPath checks that the path, relative to the variables of the
+// operation, points to a value the operation and Schema type as Upload; p is
+// the path as written in the map, used by the errors
+func (m MultipartHandler) checkUploadPath(def *ast.OperationDefinition, f string, p mapPath, path []string) (string, error) {
+	t, ok := pathType(*m.Schema, def, path)
+	if !ok {
+		return OutcomeInvalidMapPath, fmt.Errorf(InvalidMapPathMessage, p, f)
+	}
+
+	named := t
+	if nn, ok := t.(*graphql.NonNull); ok {
+		named = nn.OfType
+	}
+
+	if named != Upload {
+		return OutcomeUploadTypeMismatch, fmt.Errorf(UploadTypeMismatchMessage, p, f, t.String())
+	}
+	return OutcomeSuccess, nil
+}
+
+// pathType resolves the type of the value a path of the variables as
+// "input.files.0" points to, following the variable definitions of the
+// operation and the input objects and lists of the Schema
+func pathType(s graphql.Schema, def *ast.OperationDefinition, path []string) (graphql.Type, bool) {
+	var t graphql.Type
+	for _, vd := range def.VariableDefinitions {
+		if vd.Variable.Name.Value == path[0] {
+			t = typeFromAST(s, vd.Type)
+			break
+		}
+	}
+
+	if t == nil {
+		return nil, false
+	}
+
+	for _, p := range path[1:] {
+		if nn, ok := t.(*graphql.NonNull); ok {
+			t = nn.OfType
+		}
+
+		switch c := t.(type) {
+		case *graphql.List:
+			if _, ok := index(p); !ok {
+				return nil, false
+			}
+			t = c.OfType
+		case *graphql.InputObject:
+			f, ok := c.Fields()[p]
+			if !ok {
+				return nil, false
+			}
+			t = f.Type
+		default:
+			return nil, false
+		}
+	}
+
+	return t, true
+}
+
+// parseOperation parses the query and finds the operation that will be
+// executed, it returns nil if the query is not valid or the operation can't
+// be found
+func parseOperation(query, operationName string) *ast.OperationDefinition {
+	doc := parseQuery(query)
+	if doc == nil {
+		return nil
+	}
+
+	return findOperation(doc, operationName)
+}
+
+// parseQuery parses the query without validating it, it returns nil if the
+// query can't be parsed
+func parseQuery(query string) *ast.Document {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query)}),
+	})
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+// findOperation finds the operation that will be executed in the document
+func findOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, d := range doc.Definitions {
+		op, ok := d.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if operationName == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+			continue
+		}
+
+		if op.Name != nil && op.Name.Value == operationName {
+			return op
+		}
+	}
+
+	return found
+}
+
+func typeFromAST(s graphql.Schema, t ast.Type) graphql.Type {
+	switch t := t.(type) {
+	case *ast.List:
+		if inner := typeFromAST(s, t.Type); inner != nil {
+			return graphql.NewList(inner)
+		}
+	case *ast.NonNull:
+		if inner := typeFromAST(s, t.Type); inner != nil {
+			return graphql.NewNonNull(inner)
+		}
+	case *ast.Named:
+		return s.Type(t.Name.Value)
+	}
+	return nil
+}
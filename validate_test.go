@@ -0,0 +1,235 @@
+package graphqlmultipart_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	graphqlmultipart "github.com/lucassabreu/graphql-multipart-middleware"
+	"github.com/lucassabreu/graphql-multipart-middleware/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newValidatingHandler(t *testing.T, readFiles bool) http.Handler {
+	hooks := graphqlmultipart.Hooks{}
+	if !readFiles {
+		hooks.OnFile = func(ctx context.Context, field string, header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+			t.Fatal("files should not be read")
+			return body, nil
+		}
+	}
+
+	return graphqlmultipart.NewHandler(
+		&testutil.Schema,
+		1*1024,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("should not have forwarded the request"))
+		}),
+		graphqlmultipart.WithHooks(hooks),
+		graphqlmultipart.WithEarlyValidation(),
+	)
+}
+
+func TestEarlyValidation(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	cases := map[string]test{
+		"unknown_variable": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null,"other":null}}`,
+					"map":        `{"file":["variables.other"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables.other", "file"),
+		},
+		"not_a_variable": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}`,
+					"map":        `{"file":["query"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "query", "file"),
+		},
+		"variable_does_not_accept_upload": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($file:Upload, $skip:Boolean!) { upload(file: $file) @skip(if: $skip) { filename } }","variables":{"file":null,"skip":false}}`,
+					"map":        `{"file":["variables.skip"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.UploadTypeMismatchMessage, "variables.skip", "file", "Boolean!"),
+		},
+		"batching_invalid_path": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}},
+						{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}}
+					]`,
+					"map": `{"file":["0.variables.file","2.variables.file"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: "[" + getJSONError(graphqlmultipart.InvalidMapPathMessage, "2.variables.file", "file") + "]",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newValidatingHandler(t, false).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestEarlyValidation_InvalidQueries(t *testing.T) {
+	cases := map[string]string{
+		"syntax":     `{"query":"query($file:Upload) { upload(file: $file){ filename }","variables":{"file":null}}`,
+		"validation": `{"query":"query($file:Upload) { upload(file: $file){ missing } }","variables":{"file":null}}`,
+	}
+
+	for name, ops := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newValidatingHandler(t, false).ServeHTTP(resp, newFileUploadRequest(
+				map[string]string{"operations": ops, "map": `{"file":["variables.file"]}`},
+				map[string]string{"file": "handler.go"},
+			))
+
+			var res struct {
+				Data   interface{}
+				Errors []struct {
+					Message   string
+					Locations []interface{}
+				}
+			}
+			require.NoError(t, json.NewDecoder(resp.Result().Body).Decode(&res))
+			require.Nil(t, res.Data)
+			require.NotEmpty(t, res.Errors)
+			require.NotEmpty(t, res.Errors[0].Locations)
+		})
+	}
+}
+
+func TestEarlyValidation_AcceptsUploads(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	cases := map[string]test{
+		"upload": test{
+			req:   newSimpleUploadRequest(),
+			respo: `{"data":{"upload":{"filename":"handler.go","size":` + handlerSize(t) + `}}}`,
+		},
+		"list": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($files:[Upload]) { uploads(files: $files){ filename } }","variables":{"files":[null]}}`,
+					"map":        `{"file":["variables.files.0"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `{"data":{"uploads":[{"filename":"handler.go"}]}}`,
+		},
+		"input_object": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `{"query":"query($input:SpecialUploadInput) { specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null]}}}`,
+					"map":        `{"file":["variables.input.files.0"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `{"data":{"specialUploads":[{"filename":"handler.go"}]}}`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newValidatingHandler(t, true).ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}
+
+func TestUploadTypes(t *testing.T) {
+	type test struct {
+		req   *http.Request
+		respo string
+	}
+
+	special := `{"query":"mutation($input:SpecialUploadInput) { specialUploads(input: $input){ filename } }","variables":{"input":{"name":"a","files":[null]}}}`
+	cases := map[string]test{
+		"input_field": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input.name"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.UploadTypeMismatchMessage, "variables.input.name", "file", "String!"),
+		},
+		"list": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input.files"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.UploadTypeMismatchMessage, "variables.input.files", "file", "[Upload]"),
+		},
+		"unknown_input_field": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input.other"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables.input.other", "file"),
+		},
+		"inside_scalar": test{
+			req: newFileUploadRequest(
+				map[string]string{"operations": special, "map": `{"file":["variables.input.name.0"]}`},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: getJSONError(graphqlmultipart.InvalidMapPathMessage, "variables.input.name.0", "file"),
+		},
+		"batching_isolates_errors": test{
+			req: newFileUploadRequest(
+				map[string]string{
+					"operations": `[
+						{"query":"query($file:Upload) { upload(file: $file){ filename } }","variables":{"file":null}},
+						{"query":"query($size:Int, $file:Upload) { upload(file: $file) @include(if: true) { filename } }","variables":{"file":null,"size":null}}
+					]`,
+					"map": `{"file":["0.variables.file","1.variables.size"]}`,
+				},
+				map[string]string{"file": "handler.go"},
+			),
+			respo: `[
+				{"data":{"upload":{"filename":"handler.go"}}},
+				` + getJSONError(graphqlmultipart.UploadTypeMismatchMessage, "1.variables.size", "file", "Int") + `
+			]`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			newPolicyHandler().ServeHTTP(resp, test.req)
+			body, _ := ioutil.ReadAll(resp.Result().Body)
+			require.JSONEq(t, test.respo, string(body))
+		})
+	}
+}